@@ -0,0 +1,135 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// airportsTSV is the table of the major airports: IATA code, ICAO code, name,
+// city, country code and coordinates separated by tabs.
+//
+//go:embed airports.tsv
+var airportsTSV string
+
+// airportSuggestionLimit is the number of airports suggested for an unknown
+// airport code.
+const airportSuggestionLimit = 5
+
+// Airport is an airport of the embedded airport table.
+type Airport struct {
+	IATA    string
+	ICAO    string
+	Name    string
+	City    string
+	Country string
+	Lat     float64
+	Lon     float64
+}
+
+// String is the codes and the name of the airport, e.g.
+// "LHR/EGLL Heathrow Airport, London, GB".
+func (a Airport) String() string {
+	return fmt.Sprintf("%s/%s %s, %s, %s", a.IATA, a.ICAO, a.Name, a.City, a.Country)
+}
+
+var (
+	airportsOnce sync.Once
+	airports     []Airport
+)
+
+// Airports returns the embedded airport table.
+func Airports() []Airport {
+	airportsOnce.Do(func() {
+		for line, row := range strings.Split(strings.TrimSpace(airportsTSV), "\n") {
+			if strings.HasPrefix(row, "#") {
+				continue
+			}
+			fields := strings.Split(row, "\t")
+			if len(fields) != 7 {
+				panic(fmt.Sprintf("airports.tsv:%d: invalid airport", line+1))
+			}
+			lat, errLat := strconv.ParseFloat(fields[5], 64)
+			lon, errLon := strconv.ParseFloat(fields[6], 64)
+			if errLat != nil || errLon != nil {
+				panic(fmt.Sprintf("airports.tsv:%d: invalid coordinates", line+1))
+			}
+			airports = append(airports, Airport{
+				IATA:    fields[0],
+				ICAO:    fields[1],
+				Name:    fields[2],
+				City:    fields[3],
+				Country: fields[4],
+				Lat:     lat,
+				Lon:     lon,
+			})
+		}
+	})
+	return airports
+}
+
+// FindAirport finds the airport of an IATA code like LHR or an ICAO code like
+// EGLL. The error of an unknown code lists the closest airports.
+func FindAirport(code string) (Airport, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	for _, airport := range Airports() {
+		if airport.IATA == code || airport.ICAO == code {
+			return airport, nil
+		}
+	}
+
+	lines := []string{fmt.Sprintf("unknown airport %s", code)}
+	if suggestions := SuggestAirports(code); len(suggestions) > 0 {
+		lines[0] += ", did you mean:"
+		for _, airport := range suggestions {
+			lines = append(lines, "  "+airport.String())
+		}
+	}
+	return Airport{}, fmt.Errorf("%s", strings.Join(lines, "\n"))
+}
+
+// SuggestAirports returns the airports closest to an unknown code: the codes
+// with a typo and the airports whose name or city contains the text.
+func SuggestAirports(text string) []Airport {
+	folded := Fold(text)
+	if folded == "" {
+		return nil
+	}
+
+	type suggestion struct {
+		Airport Airport
+		Score   int
+	}
+	var suggestions []suggestion
+	for _, airport := range Airports() {
+		score := minInt(
+			Distance(folded, strings.ToLower(airport.IATA), 1),
+			Distance(folded, strings.ToLower(airport.ICAO), 1),
+		)
+		if len(folded) > 2 && strings.Contains(Fold(airport.Name+" "+airport.City), folded) {
+			score = 0
+		}
+		if score <= 1 {
+			suggestions = append(suggestions, suggestion{Airport: airport, Score: score})
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		if suggestions[i].Score != suggestions[j].Score {
+			return suggestions[i].Score < suggestions[j].Score
+		}
+		return suggestions[i].Airport.IATA < suggestions[j].Airport.IATA
+	})
+	if len(suggestions) > airportSuggestionLimit {
+		suggestions = suggestions[:airportSuggestionLimit]
+	}
+
+	found := make([]Airport, 0, len(suggestions))
+	for _, s := range suggestions {
+		found = append(found, s.Airport)
+	}
+	return found
+}
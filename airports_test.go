@@ -0,0 +1,48 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestFindAirport(t *testing.T) {
+	if len(Airports()) < 100 {
+		t.Error("Error in airport table")
+	}
+
+	for _, code := range []string{"LHR", "egll", " lhr "} {
+		airport, err := FindAirport(code)
+		if err != nil || airport.ICAO != "EGLL" || airport.City != "London" || airport.Lat != 51.47 {
+			t.Errorf("Error in airport %q", code)
+		}
+	}
+
+	_, err := FindAirport("LHX")
+	if err == nil || !strings.HasPrefix(err.Error(), "unknown airport LHX, did you mean:") || !strings.Contains(err.Error(), "  LHR/EGLL Heathrow Airport, London, GB") {
+		t.Error("Error in unknown airport", err)
+	}
+
+	if suggestions := SuggestAirports("heathrow"); len(suggestions) != 1 || suggestions[0].IATA != "LHR" {
+		t.Error("Error in airport name suggestions")
+	}
+
+	if suggestions := SuggestAirports("EGLX"); len(suggestions) != 2 || suggestions[0].ICAO != "EGLC" || suggestions[1].ICAO != "EGLL" {
+		t.Error("Error in ICAO suggestions")
+	}
+
+	if _, err := FindAirport("QQQQQQ"); err == nil || err.Error() != "unknown airport QQQQQQ" {
+		t.Error("Error in airport without suggestions", err)
+	}
+}
+
+func TestAirportLookup(t *testing.T) {
+	l, err := NewLookup(LookupValues{Airport: "egll"})
+	if err != nil || l.Kind != LookupCoordinates || l.Lat != 51.47 || l.Lon != -0.4543 || l.Airport != "EGLL" ||
+		l.Place != "LHR/EGLL Heathrow Airport, London, GB" || l.Details() != "coordinates 51.47,-0.4543 (airport EGLL)" {
+		t.Error("Error in airport lookup")
+	}
+
+	if _, err := NewLookup(LookupValues{Airport: "LHR", At: "9q8yyk"}); err == nil || !strings.Contains(err.Error(), "position, airport") {
+		t.Error("Error in airport with position")
+	}
+}
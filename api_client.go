@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/belovai/goweather/goopenweathermapapi"
+)
+
+const userAgent = "goweather (+https://github.com/belovai/goweather)"
+
+// searchTTL is how long the results of city searches and geocoding are
+// cached, the places rarely change.
+const searchTTL = 30 * 24 * time.Hour
+
+const (
+	retryBaseDelay = 500 * time.Millisecond
+	retryMaxDelay  = 10 * time.Second
+)
+
+// NewAPIClient builds the api client from the --api-url, --timeout, --proxy,
+// --ca-cert and --retries options.
+func NewAPIClient() (*goopenweathermapapi.Client, error) {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if *Proxy != "" {
+		proxyURL, err := url.Parse(*Proxy)
+		if err != nil {
+			return nil, &UsageError{Message: fmt.Sprintf("invalid proxy url: %s", *Proxy)}
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	if *CACert != "" {
+		pem, err := os.ReadFile(*CACert)
+		if err != nil {
+			return nil, err
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("no certificates found in " + *CACert)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	options := []goopenweathermapapi.Option{
+		goopenweathermapapi.WithTransport(transport),
+		goopenweathermapapi.WithTimeout(*Timeout),
+		goopenweathermapapi.WithUserAgent(userAgent),
+		goopenweathermapapi.WithRetry(goopenweathermapapi.RetryPolicy{
+			MaxRetries: *Retries,
+			BaseDelay:  retryBaseDelay,
+			MaxDelay:   retryMaxDelay,
+		}),
+	}
+	if *APIURL != "" {
+		options = append(options, goopenweathermapapi.WithBaseURL(*APIURL))
+	}
+
+	return goopenweathermapapi.NewClient(*AppID, options...), nil
+}
+
+// NewCachedClient wraps the api client with the response cache of the
+// --no-cache, --refresh, --cache-ttl, --max-stale and --cache-only options.
+func NewCachedClient() (*CachedClient, error) {
+	client, err := NewAPIClient()
+	if err != nil {
+		return nil, err
+	}
+
+	cached := &CachedClient{Client: client, Refresh: *Refresh, MaxStale: *MaxStale, CacheOnly: *CacheOnly}
+	if *NoCache {
+		if *CacheOnly {
+			return nil, &UsageError{Message: "--cache-only can not be used with --no-cache"}
+		}
+		return cached, nil
+	}
+
+	dir, err := DefaultCacheDir()
+	if err != nil {
+		log.Println("Cache:", err)
+		return cached, nil
+	}
+	cached.Cache = &Cache{Dir: dir, TTL: *CacheTTL}
+	cached.BackgroundRefresh = func(key string) {
+		refreshQueue.Add(cached.Cache, key)
+	}
+
+	if keys := BackgroundRefreshKeys(); keys != nil {
+		cached.BackgroundRefresh = nil
+		cached.RefreshKeys = keys
+	}
+
+	return cached, nil
+}
+
+// NewSearchClient is NewCachedClient with the cache TTL of searchTTL.
+func NewSearchClient() (*CachedClient, error) {
+	client, err := NewCachedClient()
+	if err != nil {
+		return nil, err
+	}
+	if client.Cache != nil {
+		client.Cache = &Cache{Dir: client.Cache.Dir, TTL: searchTTL}
+	}
+	return client, nil
+}
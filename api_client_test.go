@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/belovai/goweather/goopenweathermapapi"
+)
+
+func TestStandInServer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("User-Agent") != userAgent {
+			t.Error("Error in user agent")
+		}
+		switch r.URL.Query().Get("q") {
+		case "London,gb":
+			w.Write([]byte(weatherJson))
+		case "Broken":
+			w.Write([]byte(`{"main":`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"cod":"404","message":"city not found"}`))
+		}
+	}))
+	defer server.Close()
+
+	client := goopenweathermapapi.NewClient("appid",
+		goopenweathermapapi.WithBaseURL(server.URL),
+		goopenweathermapapi.WithTimeout(time.Second),
+		goopenweathermapapi.WithUserAgent(userAgent),
+	)
+	ctx := context.Background()
+
+	weather, err := client.CurrentWeather(ctx, Lookup{Kind: LookupCity, City: "London,gb"}.Location(), "metric", "")
+	if err != nil || weather.Name != "London" {
+		t.Error("Error in successful request")
+	}
+
+	_, err = client.CurrentWeather(ctx, Lookup{Kind: LookupCity, City: "Nowhere"}.Location(), "metric", "")
+	var apiError *goopenweathermapapi.Error
+	if !errors.Is(err, goopenweathermapapi.ErrCityNotFound) || !errors.As(err, &apiError) {
+		t.Fatal("Error in city not found")
+	}
+
+	if apiError.Cod != "404" || apiError.Message != "city not found" || apiError.StatusCode != 404 {
+		t.Error("Error in api error fields")
+	}
+
+	_, err = client.CurrentWeather(ctx, Lookup{Kind: LookupCity, City: "Broken"}.Location(), "metric", "")
+	if !errors.Is(err, goopenweathermapapi.ErrDecode) {
+		t.Error("Error in decode failure")
+	}
+
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = client.CurrentWeather(canceled, Lookup{Kind: LookupCity, City: "London,gb"}.Location(), "metric", "")
+	if !errors.Is(err, goopenweathermapapi.ErrNetwork) || !errors.Is(err, context.Canceled) {
+		t.Error("Error in canceled request")
+	}
+}
+
+func TestRetry(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch {
+		case r.URL.Query().Get("q") == "Limited":
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"cod":429,"message":"Your account is temporary blocked"}`))
+		case requests == 1:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			w.Write([]byte(weatherJson))
+		}
+	}))
+	defer server.Close()
+
+	client := goopenweathermapapi.NewClient("appid",
+		goopenweathermapapi.WithBaseURL(server.URL),
+		goopenweathermapapi.WithRetry(goopenweathermapapi.RetryPolicy{MaxRetries: 2, BaseDelay: time.Millisecond}),
+	)
+	ctx := context.Background()
+
+	if _, err := client.CurrentWeather(ctx, goopenweathermapapi.ByCityName("London,gb"), "metric", ""); err != nil || requests != 2 {
+		t.Error("Error in retry after server error")
+	}
+
+	requests = 0
+	_, err := client.CurrentWeather(ctx, goopenweathermapapi.ByCityName("Limited"), "metric", "")
+	var apiError *goopenweathermapapi.Error
+	if !errors.Is(err, goopenweathermapapi.ErrRateLimited) || !errors.As(err, &apiError) {
+		t.Fatal("Error in rate limited")
+	}
+
+	if apiError.Attempts != 3 || requests != 3 {
+		t.Error("Error in attempts")
+	}
+}
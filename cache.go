@@ -0,0 +1,424 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/belovai/goweather/goopenweathermapapi"
+)
+
+// ErrNoCachedData is returned in cache-only mode when the cache has no entry.
+var ErrNoCachedData = errors.New("no cached data yet, it is being fetched in the background")
+
+// lockTimeout is the age after which a lock is considered abandoned.
+const lockTimeout = 2 * time.Minute
+
+// Cache stores api responses as files, one file per cache key.
+type Cache struct {
+	Dir string
+	TTL time.Duration
+}
+
+// CacheEntry keeps the body as []byte, so it is stored byte for byte as the api
+// returned it.
+type CacheEntry struct {
+	Key       string    `json:"key"`
+	FetchedAt time.Time `json:"fetched_at"`
+	Body      []byte    `json:"body"`
+}
+
+// DefaultCacheDir is goweather in the XDG cache directory.
+func DefaultCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goweather"), nil
+}
+
+// CacheKey identifies a response by the endpoint, the lookup, the units and the language.
+func CacheKey(endpoint string, lookup Lookup, units, lang string) string {
+	return strings.ToLower(fmt.Sprintf("%s|%s|%s|%s", endpoint, lookup, units, lang))
+}
+
+func (c *Cache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (c *Cache) lockPath(key string) string {
+	return c.path(key) + ".lock"
+}
+
+// Lock takes the lock of the key, it returns false if somebody else holds it.
+// Locks older than lockTimeout are taken over.
+func (c *Cache) Lock(key string) bool {
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return false
+	}
+
+	for i := 0; i < 2; i++ {
+		f, err := os.OpenFile(c.lockPath(key), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return true
+		}
+
+		info, err := os.Stat(c.lockPath(key))
+		if err != nil || time.Since(info.ModTime()) < lockTimeout {
+			return false
+		}
+		os.Remove(c.lockPath(key))
+	}
+	return false
+}
+
+// Unlock releases the lock of the key.
+func (c *Cache) Unlock(key string) {
+	os.Remove(c.lockPath(key))
+}
+
+// Get returns the entry of the key regardless of its age, nil if there is none.
+func (c *Cache) Get(key string) *CacheEntry {
+	data, err := os.ReadFile(c.path(key))
+	if err != nil {
+		return nil
+	}
+
+	var entry CacheEntry
+	if err := json.Unmarshal(data, &entry); err != nil || entry.Key != key {
+		return nil
+	}
+	return &entry
+}
+
+// Put stores the body under the key. The file is replaced atomically, so
+// concurrent readers never see a partial entry.
+func (c *Cache) Put(key string, body []byte) error {
+	if err := os.MkdirAll(c.Dir, 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(CacheEntry{Key: key, FetchedAt: time.Now(), Body: body})
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(c.Dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+// Fresh reports whether the entry is younger than the ttl.
+func (e *CacheEntry) Fresh(ttl time.Duration) bool {
+	return time.Since(e.FetchedAt) < ttl
+}
+
+// Response is a response body with the time it was fetched from the api and
+// its cache key. Cached is set if it came from the cache, Stale if the api
+// could not be reached and an expired cache entry was used instead.
+// StatusCode is set if the body is an error of the api, see ErrorResponse.
+type Response struct {
+	Key        string
+	Body       []byte
+	FetchedAt  time.Time
+	Cached     bool
+	Stale      bool
+	StatusCode int
+}
+
+// ErrorResponse returns the error body of the api as a response, or nil if
+// the error has no body. Error responses are not cached.
+func ErrorResponse(key string, err error) *Response {
+	var apiError *goopenweathermapapi.Error
+	if !errors.As(err, &apiError) || apiError.Body == nil {
+		return nil
+	}
+	return &Response{Key: key, Body: apiError.Body, FetchedAt: time.Now(), StatusCode: apiError.StatusCode}
+}
+
+// Age returns how old the data of the response is.
+func (r *Response) Age() time.Duration {
+	return time.Since(r.FetchedAt)
+}
+
+// CachedClient serves api responses from the cache while they are fresh.
+// Without a Cache every request goes to the api, with Refresh the api is
+// called and the cache is updated. If the api fails with a retryable error,
+// cache entries younger than MaxStale are served as stale responses.
+//
+// With CacheOnly the api is never called, expired and missing entries are
+// handed to BackgroundRefresh instead. In the background refresh process the
+// keys of RefreshKeys are fetched from the api even in CacheOnly mode and
+// their locks are released once the api was called.
+type CachedClient struct {
+	Client            *goopenweathermapapi.Client
+	Cache             *Cache
+	Refresh           bool
+	MaxStale          time.Duration
+	CacheOnly         bool
+	BackgroundRefresh func(key string)
+	RefreshKeys       map[string]bool
+}
+
+// Get returns the response of the endpoint.
+func (c *CachedClient) Get(ctx context.Context, endpoint string, lookup Lookup, units, lang string) (*Response, error) {
+	return c.get(ctx, CacheKey(endpoint, lookup, units, lang), func() ([]byte, error) {
+		return c.Client.Get(ctx, endpoint, lookup.Location(), units, lang)
+	})
+}
+
+// Find returns the cities of the accurate search of the city name with the
+// find endpoint.
+func (c *CachedClient) Find(ctx context.Context, city string) (*goopenweathermapapi.FindResult, error) {
+	key := CacheKey("find", Lookup{Kind: LookupCity, City: city}, "", "")
+	response, err := c.get(ctx, key, func() ([]byte, error) {
+		result, err := c.Client.Find(ctx, city, goopenweathermapapi.FindAccurate, "", "")
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(result)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var result goopenweathermapapi.FindResult
+	if err := goopenweathermapapi.Decode(response.Body, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Geocode returns the places of the name with the direct geocoding api.
+func (c *CachedClient) Geocode(ctx context.Context, query string, limit int) ([]goopenweathermapapi.Place, error) {
+	key := strings.ToLower(fmt.Sprintf("geo/direct|%s|%d", query, limit))
+	return c.getPlaces(ctx, key, func() ([]goopenweathermapapi.Place, error) {
+		return c.Client.Geocode(ctx, query, limit)
+	})
+}
+
+// ReverseGeocode returns the places near the coordinates with the reverse
+// geocoding api.
+func (c *CachedClient) ReverseGeocode(ctx context.Context, lat, lon float64, limit int) ([]goopenweathermapapi.Place, error) {
+	key := fmt.Sprintf("geo/reverse|%s,%s|%d", strconv.FormatFloat(lat, 'f', -1, 64), strconv.FormatFloat(lon, 'f', -1, 64), limit)
+	return c.getPlaces(ctx, key, func() ([]goopenweathermapapi.Place, error) {
+		return c.Client.ReverseGeocode(ctx, lat, lon, limit)
+	})
+}
+
+// getPlaces serves the places of the key from the cache or calls fetch.
+func (c *CachedClient) getPlaces(ctx context.Context, key string, fetch func() ([]goopenweathermapapi.Place, error)) ([]goopenweathermapapi.Place, error) {
+	response, err := c.get(ctx, key, func() ([]byte, error) {
+		places, err := fetch()
+		if err != nil {
+			return nil, err
+		}
+		return json.Marshal(places)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var places []goopenweathermapapi.Place
+	if err := goopenweathermapapi.Decode(response.Body, &places); err != nil {
+		return nil, err
+	}
+	return places, nil
+}
+
+// get serves the key from the cache or calls fetch.
+func (c *CachedClient) get(ctx context.Context, key string, fetch func() ([]byte, error)) (*Response, error) {
+	var entry *CacheEntry
+	if c.Cache != nil {
+		entry = c.Cache.Get(key)
+	}
+
+	refresh := c.Refresh || c.RefreshKeys[key]
+	if entry != nil && !refresh && entry.Fresh(c.Cache.TTL) {
+		return &Response{Key: key, Body: entry.Body, FetchedAt: entry.FetchedAt, Cached: true}, nil
+	}
+
+	if c.CacheOnly && c.Cache != nil && !c.RefreshKeys[key] {
+		if c.BackgroundRefresh != nil {
+			c.BackgroundRefresh(key)
+		}
+		if entry == nil {
+			return nil, ErrNoCachedData
+		}
+		return &Response{Key: key, Body: entry.Body, FetchedAt: entry.FetchedAt, Cached: true}, nil
+	}
+
+	body, err := fetch()
+	if c.RefreshKeys[key] && c.Cache != nil {
+		c.Cache.Unlock(key)
+	}
+	if err != nil {
+		return c.fallback(entry, err)
+	}
+
+	return c.put(key, body), nil
+}
+
+// GetGroup returns the current weather responses of the city IDs in their
+// order. The IDs missing from the cache are requested with the group endpoint
+// in chunks of GroupLimit. Each city is cached under its own group key, apart
+// from the weather requests, since the entries of the group response differ
+// from the weather responses. A city left out by the api fails with
+// ErrCityNotFound. In CacheOnly mode only the cities of RefreshKeys are
+// requested.
+func (c *CachedClient) GetGroup(ctx context.Context, ids []int, units, lang string) ([]*Response, []error) {
+	responses := make([]*Response, len(ids))
+	errs := make([]error, len(ids))
+
+	keys := make([]string, len(ids))
+	entries := make([]*CacheEntry, len(ids))
+	var missing []int
+	for i, id := range ids {
+		keys[i] = CacheKey("group", Lookup{Kind: LookupID, ID: id}, units, lang)
+		if c.CacheOnly && !c.RefreshKeys[keys[i]] {
+			id := id
+			responses[i], errs[i] = c.get(ctx, keys[i], func() ([]byte, error) {
+				return c.getGroupCity(ctx, id, units, lang)
+			})
+			continue
+		}
+		if c.Cache != nil {
+			entries[i] = c.Cache.Get(keys[i])
+		}
+		if entries[i] != nil && !c.Refresh && !c.RefreshKeys[keys[i]] && entries[i].Fresh(c.Cache.TTL) {
+			responses[i] = &Response{Key: keys[i], Body: entries[i].Body, FetchedAt: entries[i].FetchedAt, Cached: true}
+			continue
+		}
+		missing = append(missing, i)
+	}
+
+	for start := 0; start < len(missing); start += goopenweathermapapi.GroupLimit {
+		end := start + goopenweathermapapi.GroupLimit
+		if end > len(missing) {
+			end = len(missing)
+		}
+		chunk := missing[start:end]
+
+		chunkIDs := make([]int, 0, len(chunk))
+		for _, i := range chunk {
+			chunkIDs = append(chunkIDs, ids[i])
+		}
+		bodies, err := c.getGroup(ctx, chunkIDs, units, lang)
+
+		for _, i := range chunk {
+			if c.RefreshKeys[keys[i]] && c.Cache != nil {
+				c.Cache.Unlock(keys[i])
+			}
+			switch body, ok := bodies[ids[i]]; {
+			case err != nil:
+				responses[i], errs[i] = c.fallback(entries[i], err)
+			case !ok:
+				errs[i] = groupNotFound(ids[i])
+			default:
+				responses[i] = c.put(keys[i], body)
+			}
+		}
+	}
+
+	return responses, errs
+}
+
+// getGroupCity makes a group request of a single city and returns its body.
+func (c *CachedClient) getGroupCity(ctx context.Context, id int, units, lang string) ([]byte, error) {
+	bodies, err := c.getGroup(ctx, []int{id}, units, lang)
+	if err != nil {
+		return nil, err
+	}
+	body, ok := bodies[id]
+	if !ok {
+		return nil, groupNotFound(id)
+	}
+	return body, nil
+}
+
+// groupNotFound is the error of a city which was left out of the group
+// response.
+func groupNotFound(id int) error {
+	return &NotFoundError{Lookup: Lookup{Kind: LookupID, ID: id}, Err: goopenweathermapapi.ErrCityNotFound}
+}
+
+// getGroup makes a group request and returns the body of each city by ID.
+func (c *CachedClient) getGroup(ctx context.Context, ids []int, units, lang string) (map[int][]byte, error) {
+	body, err := c.Client.Get(ctx, "group", goopenweathermapapi.ByCityIDs(ids), units, lang)
+	if err != nil {
+		return nil, err
+	}
+
+	var group struct {
+		List []json.RawMessage `json:"list"`
+	}
+	if err := goopenweathermapapi.Decode(body, &group); err != nil {
+		return nil, err
+	}
+
+	bodies := make(map[int][]byte, len(group.List))
+	for _, item := range group.List {
+		var city struct {
+			ID int `json:"id"`
+		}
+		if err := goopenweathermapapi.Decode(item, &city); err != nil {
+			return nil, err
+		}
+		bodies[city.ID] = item
+	}
+	return bodies, nil
+}
+
+// fallback serves the entry as a stale response if the api failed with a
+// retryable error and the entry is younger than MaxStale.
+func (c *CachedClient) fallback(entry *CacheEntry, err error) (*Response, error) {
+	if entry != nil && Retryable(err) && time.Since(entry.FetchedAt) < c.MaxStale {
+		return &Response{Key: entry.Key, Body: entry.Body, FetchedAt: entry.FetchedAt, Cached: true, Stale: true}, nil
+	}
+	return nil, err
+}
+
+// put caches the body fetched from the api and returns it as a response.
+func (c *CachedClient) put(key string, body []byte) *Response {
+	if c.Cache != nil {
+		if err := c.Cache.Put(key, body); err != nil {
+			log.Println("Cache:", err)
+		}
+	}
+	return &Response{Key: key, Body: body, FetchedAt: time.Now()}
+}
+
+// FormatAge formats the age of the data, e.g. "42 min ago".
+func FormatAge(age time.Duration) string {
+	switch {
+	case age < time.Minute:
+		return "less than a minute ago"
+	case age < time.Hour:
+		return fmt.Sprintf("%d min ago", int(age/time.Minute))
+	case age < 24*time.Hour:
+		return fmt.Sprintf("%d h %d min ago", int(age/time.Hour), int(age%time.Hour/time.Minute))
+	}
+	return fmt.Sprintf("%d days ago", int(age/(24*time.Hour)))
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/belovai/goweather/goopenweathermapapi"
+)
+
+func TestCache(t *testing.T) {
+	cache := &Cache{Dir: t.TempDir(), TTL: time.Minute}
+	key := CacheKey("weather", Lookup{Kind: LookupCity, City: "London,GB"}, "metric", "en")
+
+	if key != CacheKey("weather", Lookup{Kind: LookupCity, City: "london,gb"}, "metric", "en") {
+		t.Error("Error in case insensitive key")
+	}
+
+	if cache.Get(key) != nil {
+		t.Error("Error in missing entry")
+	}
+
+	if err := cache.Put(key, []byte(`{"name":"London"}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	entry := cache.Get(key)
+	if entry == nil || string(entry.Body) != `{"name":"London"}` || !entry.Fresh(cache.TTL) {
+		t.Error("Error in stored entry")
+	}
+
+	entry.FetchedAt = time.Now().Add(-2 * time.Minute)
+	if entry.Fresh(cache.TTL) {
+		t.Error("Error in expired entry")
+	}
+}
+
+func TestCachedClient(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(weatherJson))
+	}))
+	defer server.Close()
+
+	client := &CachedClient{
+		Client: goopenweathermapapi.NewClient("appid", goopenweathermapapi.WithBaseURL(server.URL)),
+		Cache:  &Cache{Dir: t.TempDir(), TTL: time.Minute},
+	}
+	lookup := Lookup{Kind: LookupID, ID: 2643743}
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		if response, err := client.Get(ctx, "weather", lookup, "metric", ""); err != nil || string(response.Body) != weatherJson {
+			t.Fatal("Error in cached request")
+		}
+	}
+
+	if requests != 1 {
+		t.Error("Error in cache hit")
+	}
+
+	client.Refresh = true
+	client.Get(ctx, "weather", lookup, "metric", "")
+	if requests != 2 {
+		t.Error("Error in refresh")
+	}
+
+	client.Get(ctx, "weather", lookup, "imperial", "")
+	if requests != 3 {
+		t.Error("Error in cache key by units")
+	}
+}
+
+func TestStaleFallback(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	cache := &Cache{Dir: t.TempDir(), TTL: time.Minute}
+	client := &CachedClient{
+		Client:   goopenweathermapapi.NewClient("appid", goopenweathermapapi.WithBaseURL(server.URL)),
+		Cache:    cache,
+		MaxStale: time.Hour,
+	}
+	lookup := Lookup{Kind: LookupCity, City: "London,gb"}
+	ctx := context.Background()
+
+	if _, err := client.Get(ctx, "weather", lookup, "metric", ""); err == nil {
+		t.Error("Error in failure without cache")
+	}
+
+	cache.TTL = 0
+	cache.Put(CacheKey("weather", lookup, "metric", ""), []byte(weatherJson))
+
+	response, err := client.Get(ctx, "weather", lookup, "metric", "")
+	if err != nil || !response.Stale || string(response.Body) != weatherJson {
+		t.Error("Error in stale fallback")
+	}
+
+	client.MaxStale = 0
+	if _, err := client.Get(ctx, "weather", lookup, "metric", ""); err == nil {
+		t.Error("Error in disabled fallback")
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	if FormatAge(42*time.Minute+10*time.Second) != "42 min ago" {
+		t.Error("Error in minutes")
+	}
+
+	if FormatAge(3*time.Hour+5*time.Minute) != "3 h 5 min ago" {
+		t.Error("Error in hours")
+	}
+
+	if FormatAge(50*time.Hour) != "2 days ago" {
+		t.Error("Error in days")
+	}
+}
+
+func TestCacheOnly(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("Error in cache-only mode, the api was called")
+	}))
+	defer server.Close()
+
+	var refreshed []string
+	cache := &Cache{Dir: t.TempDir(), TTL: time.Minute}
+	client := &CachedClient{
+		Client:    goopenweathermapapi.NewClient("appid", goopenweathermapapi.WithBaseURL(server.URL)),
+		Cache:     cache,
+		CacheOnly: true,
+		BackgroundRefresh: func(key string) {
+			refreshed = append(refreshed, key)
+		},
+	}
+	lookup := Lookup{Kind: LookupCity, City: "London,gb"}
+	key := CacheKey("weather", lookup, "metric", "")
+	ctx := context.Background()
+
+	if _, err := client.Get(ctx, "weather", lookup, "metric", ""); err != ErrNoCachedData || len(refreshed) != 1 {
+		t.Error("Error in missing entry")
+	}
+
+	cache.Put(key, []byte(weatherJson))
+	if response, err := client.Get(ctx, "weather", lookup, "metric", ""); err != nil || !response.Cached || len(refreshed) != 1 {
+		t.Error("Error in fresh entry")
+	}
+
+	cache.TTL = 0
+	if response, err := client.Get(ctx, "weather", lookup, "metric", ""); err != nil || string(response.Body) != weatherJson || len(refreshed) != 2 {
+		t.Error("Error in expired entry")
+	}
+}
+
+func TestCacheLock(t *testing.T) {
+	cache := &Cache{Dir: t.TempDir()}
+
+	if !cache.Lock("key") {
+		t.Fatal("Error in first lock")
+	}
+
+	if cache.Lock("key") {
+		t.Error("Error in concurrent lock")
+	}
+
+	cache.Unlock("key")
+	if !cache.Lock("key") {
+		t.Error("Error in lock after unlock")
+	}
+}
+
+func TestBackgroundRefresh(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Write([]byte(weatherJson))
+	}))
+	defer server.Close()
+
+	cache := &Cache{Dir: t.TempDir(), TTL: time.Minute}
+	london := Lookup{Kind: LookupCity, City: "London,gb"}
+	paris := Lookup{Kind: LookupCity, City: "Paris,fr"}
+	londonKey := CacheKey("weather", london, "metric", "")
+	parisKey := CacheKey("weather", paris, "metric", "")
+
+	queue := &RefreshQueue{}
+	queue.Add(cache, londonKey)
+	queue.Add(cache, londonKey)
+	queue.Add(cache, parisKey)
+	if len(queue.keys) != 2 || cache.Lock(londonKey) {
+		t.Error("Error in refresh queue")
+	}
+	cache.Unlock(parisKey)
+
+	client := &CachedClient{
+		Client:      goopenweathermapapi.NewClient("appid", goopenweathermapapi.WithBaseURL(server.URL)),
+		Cache:       cache,
+		CacheOnly:   true,
+		RefreshKeys: map[string]bool{londonKey: true},
+	}
+	ctx := context.Background()
+
+	if response, err := client.Get(ctx, "weather", london, "metric", ""); err != nil || response.Cached || requests != 1 {
+		t.Error("Error in refreshed key")
+	}
+	if !cache.Lock(londonKey) {
+		t.Error("Error in unlock of refreshed key")
+	}
+
+	if _, err := client.Get(ctx, "weather", paris, "metric", ""); err != ErrNoCachedData || requests != 1 {
+		t.Error("Error in key of another process")
+	}
+}
@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/belovai/goweather/goopenweathermapapi"
+)
+
+// cityIndexHeader is the first line of the city index, it changes with the
+// format of the file.
+const cityIndexHeader = "goweather cities 1"
+
+// citySearchLimit is the number of cities shown by cities search.
+const citySearchLimit = 10
+
+// City is a city of the OpenWeatherMap city list.
+type City struct {
+	ID      int
+	Name    string
+	State   string
+	Country string
+	Lat     float64
+	Lon     float64
+
+	folded string
+}
+
+func (c City) String() string {
+	place := c.Country
+	if c.State != "" {
+		place += "/" + c.State
+	}
+	return fmt.Sprintf("%d %s %s (%.2f,%.2f)", c.ID, c.Name, place, c.Lat, c.Lon)
+}
+
+// DefaultCityIndexPath is cities.tsv.gz in the cache directory.
+func DefaultCityIndexPath() (string, error) {
+	dir, err := DefaultCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "cities.tsv.gz"), nil
+}
+
+// ReadCityList reads the city.list.json of OpenWeatherMap, gzipped or not,
+// from http://bulk.openweathermap.org/sample/
+func ReadCityList(r io.Reader) ([]City, error) {
+	buffered := bufio.NewReader(r)
+	if magic, err := buffered.Peek(2); err == nil && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		r = gz
+	} else {
+		r = buffered
+	}
+
+	decoder := json.NewDecoder(r)
+	if token, err := decoder.Token(); err != nil || token != json.Delim('[') {
+		return nil, errors.New("invalid city list, expected a JSON array")
+	}
+
+	var cities []City
+	for decoder.More() {
+		var item struct {
+			ID      int    `json:"id"`
+			Name    string `json:"name"`
+			State   string `json:"state"`
+			Country string `json:"country"`
+			Coord   struct {
+				Lat float64 `json:"lat"`
+				Lon float64 `json:"lon"`
+			} `json:"coord"`
+		}
+		if err := decoder.Decode(&item); err != nil {
+			return nil, fmt.Errorf("invalid city list: %s", err)
+		}
+		cities = append(cities, City{
+			ID:      item.ID,
+			Name:    item.Name,
+			State:   item.State,
+			Country: item.Country,
+			Lat:     item.Coord.Lat,
+			Lon:     item.Coord.Lon,
+		})
+	}
+
+	return cities, nil
+}
+
+// WriteCityIndex writes the cities as gzipped tab separated lines. The file is
+// replaced atomically like the cache entries.
+func WriteCityIndex(path string, cities []City) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".cities-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	gz := gzip.NewWriter(tmp)
+	w := bufio.NewWriter(gz)
+	fmt.Fprintln(w, cityIndexHeader)
+	clean := strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+	for _, city := range cities {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\n",
+			city.ID,
+			clean.Replace(city.Name),
+			clean.Replace(city.State),
+			clean.Replace(city.Country),
+			strconv.FormatFloat(city.Lat, 'f', -1, 64),
+			strconv.FormatFloat(city.Lon, 'f', -1, 64),
+		)
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// LoadCityIndex reads the city index written by WriteCityIndex.
+func LoadCityIndex(path string) ([]City, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, &UsageError{Message: "there is no city index yet, import it with: goweather cities import city.list.json.gz"}
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, fmt.Errorf("invalid city index %s: %s", path, err)
+	}
+	defer gz.Close()
+
+	scanner := bufio.NewScanner(gz)
+	if !scanner.Scan() || scanner.Text() != cityIndexHeader {
+		return nil, fmt.Errorf("invalid city index %s, import it again", path)
+	}
+
+	var cities []City
+	for line := 2; scanner.Scan(); line++ {
+		fields := strings.Split(scanner.Text(), "\t")
+		if len(fields) != 6 {
+			return nil, fmt.Errorf("%s:%d: invalid city", path, line)
+		}
+		id, err := strconv.Atoi(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid city ID: %s", path, line, fields[0])
+		}
+		lat, errLat := strconv.ParseFloat(fields[4], 64)
+		lon, errLon := strconv.ParseFloat(fields[5], 64)
+		if errLat != nil || errLon != nil {
+			return nil, fmt.Errorf("%s:%d: invalid coordinates", path, line)
+		}
+		cities = append(cities, City{ID: id, Name: fields[1], State: fields[2], Country: fields[3], Lat: lat, Lon: lon})
+	}
+
+	return cities, scanner.Err()
+}
+
+// cityMatch is a city found by SearchCities, the lower the score the better.
+type cityMatch struct {
+	City  City
+	Score int
+}
+
+// SearchCities returns the cities matching the query in order of relevance.
+// The query is a city name, optionally with a country code separated by comma
+// like --city. Accents and case are ignored and a few typos are tolerated:
+// exact names come first, then names starting with the query, then names with
+// typos.
+func SearchCities(cities []City, query string) []City {
+	matches := searchCities(cities, query)
+	found := make([]City, 0, len(matches))
+	for _, match := range matches {
+		found = append(found, match.City)
+	}
+	return found
+}
+
+func searchCities(cities []City, query string) []cityMatch {
+	name, country := splitCountry(query)
+	folded := Fold(name)
+	if folded == "" {
+		return nil
+	}
+
+	// Short names tolerate fewer typos, otherwise everything would match.
+	typos := 0
+	switch length := len([]rune(folded)); {
+	case length > 7:
+		typos = 2
+	case length > 3:
+		typos = 1
+	}
+
+	var matches []cityMatch
+	for i := range cities {
+		city := &cities[i]
+		if country != "" && !strings.EqualFold(city.Country, country) {
+			continue
+		}
+		if city.folded == "" {
+			city.folded = Fold(city.Name)
+		}
+
+		switch {
+		case city.folded == folded:
+			matches = append(matches, cityMatch{City: *city, Score: 0})
+		case strings.HasPrefix(city.folded, folded):
+			matches = append(matches, cityMatch{City: *city, Score: 1})
+		case typos > 0:
+			if distance := Distance(folded, city.folded, typos); distance <= typos {
+				matches = append(matches, cityMatch{City: *city, Score: 1 + distance})
+			}
+		}
+	}
+
+	sort.SliceStable(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.Score != b.Score {
+			return a.Score < b.Score
+		}
+		if len(a.City.Name) != len(b.City.Name) {
+			return len(a.City.Name) < len(b.City.Name)
+		}
+		if a.City.Name != b.City.Name {
+			return a.City.Name < b.City.Name
+		}
+		return a.City.ID < b.City.ID
+	})
+
+	return matches
+}
+
+// splitCountry splits "London,gb" into the city name and the country code.
+func splitCountry(query string) (string, string) {
+	if i := strings.LastIndex(query, ","); i >= 0 {
+		if country := strings.TrimSpace(query[i+1:]); len(country) == 2 {
+			return query[:i], country
+		}
+	}
+	return query, ""
+}
+
+// FindCity finds the city ID of a --city value in the index. Only exact
+// names are used, of a name of several cities PickCity chooses one.
+func FindCity(cities []City, query string) (City, error) {
+	var exact []City
+	for _, match := range searchCities(cities, query) {
+		if match.Score == 0 {
+			exact = append(exact, match.City)
+		}
+	}
+
+	switch len(exact) {
+	case 0:
+		return City{}, fmt.Errorf("%w in the city index: %s, see goweather cities search", goopenweathermapapi.ErrCityNotFound, query)
+	case 1:
+		return exact[0], nil
+	}
+
+	return PickCity(query, exact)
+}
+
+// ResolveCities replaces the city name lookups of the targets with city ID
+// lookups from the city index.
+func ResolveCities(targets []Target) ([]Target, error) {
+	path, err := DefaultCityIndexPath()
+	if err != nil {
+		return nil, err
+	}
+	cities, err := LoadCityIndex(path)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make([]Target, 0, len(targets))
+	for _, target := range targets {
+		if target.Lookup.Kind == LookupCity {
+			city, err := FindCity(cities, target.Lookup.City)
+			if err != nil {
+				return nil, err
+			}
+			target.Lookup = Lookup{Kind: LookupID, ID: city.ID}
+		}
+		resolved = append(resolved, target)
+	}
+	return resolved, nil
+}
+
+// RunCities runs the cities import and cities search commands, args are the
+// arguments after the subcommand.
+func RunCities(subcommand string, args []string) error {
+	if len(args) == 0 || (subcommand != "import" && subcommand != "search") {
+		return &UsageError{Message: "Unknown cities command, use: goweather cities import FILE or goweather cities search TEXT"}
+	}
+	printer, err := DataPrinter("cities " + subcommand)
+	if err != nil {
+		return err
+	}
+
+	path, err := DefaultCityIndexPath()
+	if err != nil {
+		return err
+	}
+
+	if subcommand == "import" {
+		return importCities(path, args[0], printer)
+	}
+
+	cities, err := LoadCityIndex(path)
+	if err != nil {
+		return err
+	}
+	found := SearchCities(cities, strings.Join(args, " "))
+	if len(found) > citySearchLimit {
+		found = found[:citySearchLimit]
+	}
+	return ShowCities(found, printer)
+}
+
+func importCities(path, file string, printer func(io.Writer, interface{}) error) error {
+	r := io.Reader(os.Stdin)
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	cities, err := ReadCityList(r)
+	if err != nil {
+		return err
+	}
+	if err := WriteCityIndex(path, cities); err != nil {
+		return err
+	}
+
+	if printer != nil {
+		return printer(os.Stdout, map[string]interface{}{"index": path, "cities": len(cities)})
+	}
+	fmt.Printf("Imported %d cities into %s\n", len(cities), path)
+	return nil
+}
+
+// ShowCities prints the cities found by cities search with the printer of
+// DataPrinter, or as a table if it is nil.
+func ShowCities(cities []City, printer func(io.Writer, interface{}) error) error {
+	if printer != nil {
+		return printer(os.Stdout, cityFields(cities))
+	}
+
+	if len(cities) == 0 {
+		fmt.Println("No cities found")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tName\tState\tCountry\tLat\tLon")
+	for _, city := range cities {
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%.4f\t%.4f\n", city.ID, city.Name, city.State, city.Country, city.Lat, city.Lon)
+	}
+	return tw.Flush()
+}
+
+// cityFields are the cities in the JSON output.
+func cityFields(cities []City) []map[string]interface{} {
+	transformer := make([]map[string]interface{}, 0, len(cities))
+	for _, city := range cities {
+		transformer = append(transformer, map[string]interface{}{
+			"id":      city.ID,
+			"name":    city.Name,
+			"state":   city.State,
+			"country": city.Country,
+			"lat":     city.Lat,
+			"lon":     city.Lon,
+		})
+	}
+	return transformer
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const cityListJson = `[
+{"id":2643743,"name":"London","state":"","country":"GB","coord":{"lon":-0.12574,"lat":51.50853}},
+{"id":6058560,"name":"London","state":"","country":"CA","coord":{"lon":-81.23304,"lat":42.98339}},
+{"id":3448439,"name":"São Paulo","state":"","country":"BR","coord":{"lon":-46.63611,"lat":-23.5475}},
+{"id":4951788,"name":"Springfield","state":"MA","country":"US","coord":{"lon":-72.58981,"lat":42.10148}},
+{"id":4409896,"name":"Springfield","state":"MO","country":"US","coord":{"lon":-93.29824,"lat":37.21533}},
+{"id":2643741,"name":"City of London","state":"","country":"GB","coord":{"lon":-0.09184,"lat":51.51279}},
+{"id":2643734,"name":"Londonderry County Borough","state":"","country":"GB","coord":{"lon":-7.30934,"lat":54.99721}}
+]`
+
+func TestCityIndex(t *testing.T) {
+	var gzipped bytes.Buffer
+	gz := gzip.NewWriter(&gzipped)
+	gz.Write([]byte(cityListJson))
+	gz.Close()
+
+	cities, err := ReadCityList(&gzipped)
+	if err != nil || len(cities) != 7 || cities[2].Name != "São Paulo" || cities[3].State != "MA" {
+		t.Fatal("Error in city list")
+	}
+
+	path := filepath.Join(t.TempDir(), "cities.tsv.gz")
+	if err := WriteCityIndex(path, cities); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := LoadCityIndex(path)
+	if err != nil || len(loaded) != 7 || loaded[2] != cities[2] || loaded[0].Lat != 51.50853 {
+		t.Fatal("Error in city index")
+	}
+
+	if _, err := ReadCityList(strings.NewReader(`{"id":1}`)); err == nil {
+		t.Error("Error in invalid city list")
+	}
+}
+
+func TestSearchCities(t *testing.T) {
+	cities, _ := ReadCityList(strings.NewReader(cityListJson))
+
+	found := SearchCities(cities, "london")
+	if len(found) != 3 || found[0].ID != 2643743 || found[1].ID != 6058560 || found[2].ID != 2643734 {
+		t.Error("Error in exact and prefix matches")
+	}
+
+	if found := SearchCities(cities, "Lodnon,ca"); len(found) != 1 || found[0].ID != 6058560 {
+		t.Error("Error in typo with country")
+	}
+
+	if found := SearchCities(cities, "sao paulo"); len(found) != 1 || found[0].ID != 3448439 {
+		t.Error("Error in accent insensitive search")
+	}
+
+	if found := SearchCities(cities, "Lon"); len(found) != 3 {
+		t.Error("Error in short prefix")
+	}
+
+	if city, err := FindCity(cities, "London,gb"); err != nil || city.ID != 2643743 {
+		t.Error("Error in city with country")
+	}
+
+	pick, format := 0, "json"
+	Pick, Format = &pick, &format
+
+	if _, err := FindCity(cities, "Springfield"); ExitCode(err) != ExitAmbiguousCity {
+		t.Error("Error in ambiguous city")
+	}
+
+	pick = 2
+	if city, err := FindCity(cities, "Springfield"); err != nil || city.ID != 4951788 {
+		t.Error("Error in picked city")
+	}
+
+	if _, err := FindCity(cities, "Lodnon"); ExitCode(err) != ExitCityNotFound {
+		t.Error("Error in city not in index")
+	}
+}
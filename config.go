@@ -0,0 +1,190 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Config is the goweather config file. It is an ini style file with the
+// default options at the top, named locations in [location NAME] sections and
+// groups of them in [group NAME] sections:
+//
+//	appid = YOUR_APP_ID
+//	units = metric
+//	location = home
+//
+//	[location home]
+//	city = London,gb
+//
+//	[location cabin]
+//	lat = 61.12
+//	lon = 10.47
+//	units = imperial
+//	lang = no
+//
+//	[group all]
+//	locations = home, cabin
+type Config struct {
+	Path      string
+	Defaults  map[string]string
+	Locations map[string]map[string]string
+	Groups    map[string][]string
+}
+
+// locationKeys are the keys allowed in a [location NAME] section.
+var locationKeys = []string{"city", "id", "lat", "lon", "at", "airport", "zip", "units", "lang"}
+
+// DefaultConfigPath is goweather/config.ini in the XDG config directory.
+func DefaultConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "goweather", "config.ini"), nil
+}
+
+// LoadConfig reads the config file. A missing file is an empty config unless
+// it was asked for explicitly.
+func LoadConfig(path string, explicit bool) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && !explicit {
+			return newConfig(path), nil
+		}
+		return nil, err
+	}
+	defer f.Close()
+
+	return ParseConfig(f, path)
+}
+
+func newConfig(path string) *Config {
+	return &Config{
+		Path:      path,
+		Defaults:  map[string]string{},
+		Locations: map[string]map[string]string{},
+		Groups:    map[string][]string{},
+	}
+}
+
+// ParseConfig parses the config file, path is used in the error messages.
+func ParseConfig(r io.Reader, path string) (*Config, error) {
+	config := newConfig(path)
+	section := config.Defaults
+	sectionKind, sectionName := "", ""
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") || strings.HasPrefix(text, ";") {
+			continue
+		}
+
+		if strings.HasPrefix(text, "[") {
+			if !strings.HasSuffix(text, "]") {
+				return nil, fmt.Errorf("%s:%d: invalid section: %s", path, line, text)
+			}
+			fields := strings.Fields(text[1 : len(text)-1])
+			if len(fields) != 2 || (fields[0] != "location" && fields[0] != "group") {
+				return nil, fmt.Errorf("%s:%d: invalid section, use [location NAME] or [group NAME]: %s", path, line, text)
+			}
+			sectionKind, sectionName = fields[0], fields[1]
+			_, isLocation := config.Locations[sectionName]
+			_, isGroup := config.Groups[sectionName]
+			if isLocation || isGroup {
+				return nil, fmt.Errorf("%s:%d: duplicate location or group: %s", path, line, sectionName)
+			}
+			section = map[string]string{}
+			if sectionKind == "location" {
+				config.Locations[sectionName] = section
+			} else {
+				config.Groups[sectionName] = nil
+			}
+			continue
+		}
+
+		i := strings.Index(text, "=")
+		if i < 0 {
+			return nil, fmt.Errorf("%s:%d: expected key = value: %s", path, line, text)
+		}
+		key := strings.TrimSpace(text[:i])
+		value := strings.Trim(strings.TrimSpace(text[i+1:]), `"`)
+
+		switch sectionKind {
+		case "location":
+			if !contains(locationKeys, key) {
+				return nil, fmt.Errorf("%s:%d: unknown key in location %s: %s", path, line, sectionName, key)
+			}
+		case "group":
+			if key != "locations" {
+				return nil, fmt.Errorf("%s:%d: unknown key in group %s, use locations: %s", path, line, sectionName, key)
+			}
+			config.Groups[sectionName] = splitNames(value)
+			continue
+		default:
+			if !contains(configKeys(), key) {
+				return nil, fmt.Errorf("%s:%d: unknown key: %s", path, line, key)
+			}
+		}
+		section[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, name := range config.GroupNames() {
+		if len(config.Groups[name]) == 0 {
+			return nil, fmt.Errorf("%s: empty group: %s", path, name)
+		}
+		for _, location := range config.Groups[name] {
+			if _, ok := config.Locations[location]; !ok {
+				return nil, fmt.Errorf("%s: unknown location in group %s: %s", path, name, location)
+			}
+		}
+	}
+
+	for _, name := range splitNames(config.Defaults["location"]) {
+		_, isLocation := config.Locations[name]
+		_, isGroup := config.Groups[name]
+		if !isLocation && !isGroup {
+			return nil, fmt.Errorf("%s: unknown default location: %s", path, name)
+		}
+	}
+
+	return config, nil
+}
+
+// LocationNames returns the names of the locations in alphabetical order.
+func (c *Config) LocationNames() []string {
+	names := make([]string, 0, len(c.Locations))
+	for name := range c.Locations {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GroupNames returns the names of the groups in alphabetical order.
+func (c *Config) GroupNames() []string {
+	names := make([]string, 0, len(c.Groups))
+	for name := range c.Groups {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+const configIni = `# goweather
+appid = secret
+units = metric
+location = home
+
+[location home]
+city = London,gb
+
+[location cabin]
+lat = 61.12
+lon = 10.47
+units = imperial
+
+[group all]
+locations = home, cabin
+`
+
+func TestParseConfig(t *testing.T) {
+	config, err := ParseConfig(strings.NewReader(configIni), "config.ini")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Defaults["appid"] != "secret" || config.Defaults["location"] != "home" {
+		t.Error("Error in defaults")
+	}
+
+	if config.Locations["home"]["city"] != "London,gb" {
+		t.Error("Error in location home")
+	}
+
+	if config.Locations["cabin"]["units"] != "imperial" || config.Locations["cabin"]["lat"] != "61.12" {
+		t.Error("Error in location cabin")
+	}
+
+	if names := config.LocationNames(); len(names) != 2 || names[0] != "cabin" || names[1] != "home" {
+		t.Error("Error in location names")
+	}
+
+	if group := config.Groups["all"]; len(group) != 2 || group[0] != "home" || group[1] != "cabin" {
+		t.Error("Error in group all")
+	}
+
+	targets, err := config.namedTargets([]string{"cabin"})
+	if err != nil || len(targets) != 1 || targets[0].Lookup.Kind != LookupCoordinates || targets[0].Location != "cabin" {
+		t.Error("Error in location targets")
+	}
+
+	targets, err = config.namedTargets([]string{"all"})
+	if err != nil || len(targets) != 2 || targets[0].Location != "home" || targets[1].Location != "cabin" {
+		t.Error("Error in group targets")
+	}
+
+	if _, _, err := config.locationSetting("units", targets); ExitCode(err) != ExitUsage ||
+		err.Error() != "the locations are fetched with the same units, but they have different ones: home: global, cabin: imperial; fetch them separately or set --units" {
+		t.Error("Error in mixed units")
+	}
+	if lang, _, err := config.locationSetting("lang", targets); err != nil || lang != "" {
+		t.Error("Error in global lang")
+	}
+	cabins, _ := config.namedTargets([]string{"cabin", "cabin"})
+	if units, locations, err := config.locationSetting("units", cabins); err != nil || units != "imperial" || locations != "cabin" {
+		t.Error("Error in same units")
+	}
+
+	if _, err := config.namedTargets([]string{"nope"}); err == nil {
+		t.Error("Error in unknown location targets")
+	}
+
+	targets, err = config.argTargets([]LookupArg{
+		{Name: "city", Value: "Paris,fr"},
+		{Name: "lat", Value: "51.51"},
+		{Name: "location", Value: "home"},
+		{Name: "lon", Value: "-0.13"},
+		{Name: "zip", Value: "94040,us"},
+	})
+	if err != nil || len(targets) != 4 ||
+		targets[0].Lookup.City != "Paris,fr" ||
+		targets[1].Location != "home" ||
+		targets[2].Lookup.Kind != LookupCoordinates ||
+		targets[3].Lookup.Zip != "94040,us" {
+		t.Error("Error in argument targets")
+	}
+
+	if _, err := config.argTargets([]LookupArg{{Name: "lat", Value: "51.51"}}); err == nil {
+		t.Error("Error in lat without lon")
+	}
+
+	targets, err = config.argTargets([]LookupArg{
+		{Name: "lat", Value: "1"},
+		{Name: "lat", Value: "2"},
+		{Name: "lon", Value: "3"},
+		{Name: "lon", Value: "4"},
+	})
+	if err != nil || len(targets) != 2 || targets[0].Lookup.Lat != 1 || targets[0].Lookup.Lon != 3 || targets[1].Lookup.Lat != 2 || targets[1].Lookup.Lon != 4 {
+		t.Error("Error in repeated lat and lon")
+	}
+
+	_, err = config.argTargets([]LookupArg{{Name: "lat", Value: "1"}, {Name: "lat", Value: "2"}, {Name: "lon", Value: "3"}})
+	if ExitCode(err) != ExitUsage || err.Error() != "--lat and --lon must be used together, got 2 --lat and 1 --lon" {
+		t.Error("Error in unpaired lat")
+	}
+
+	targets, err = config.argTargets([]LookupArg{{Name: "at", Value: "849VCWC8+R9"}})
+	if err != nil || len(targets) != 1 || targets[0].Lookup.Kind != LookupCoordinates || targets[0].Lookup.At != "849VCWC8+R9" ||
+		targets[0].Lookup.Details() != "coordinates 37.422063,-122.084063 (from 849VCWC8+R9)" {
+		t.Error("Error in at targets")
+	}
+
+	if _, err := newTargets(LookupValues{At: "9q8yyk", Lat: "1"}, ""); err == nil {
+		t.Error("Error in at with lat")
+	}
+}
+
+func TestParseConfigErrors(t *testing.T) {
+	invalid := map[string]string{
+		"config.ini:1: unknown key":                "colour = blue",
+		"config.ini:1: expected key = value":       "appid",
+		"config.ini:2: invalid section":            "\n[place home]",
+		"config.ini:2: unknown key in location":    "[location home]\nappid = x",
+		"config.ini:3: duplicate location":         "[location home]\n\n[location home]",
+		"config.ini:2: unknown key in group":       "[group all]\ncity = x",
+		"config.ini: unknown location in group":    "[group all]\nlocations = home",
+		"config.ini: empty group":                  "[group all]",
+		"config.ini: unknown default location":     "location = home",
+		"config.ini:1: invalid section, use [loca": "[location]",
+	}
+
+	for message, ini := range invalid {
+		_, err := ParseConfig(strings.NewReader(ini), "config.ini")
+		if err == nil || !strings.HasPrefix(err.Error(), message) {
+			t.Errorf("Error in %q: %v", message, err)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+func init() {
+	RegisterFormat(OutputFormat{
+		Name:        "csv",
+		Description: "the records of the JSON output as CSV with a header, see --delimiter",
+		New:         func() OutputWriterInterface { return &CsvOutputWriter{} },
+	})
+	RegisterFormat(OutputFormat{
+		Name:        "tsv",
+		Description: "the records of the JSON output as tab separated values with a header",
+		New:         func() OutputWriterInterface { return &CsvOutputWriter{Delimiter: '\t'} },
+	})
+}
+
+// CsvOutputWriter prints the records of the JSON output as a header and a row
+// per record. The columns are RecordColumns for every command, the values of
+// the fields which do not apply are empty. The delimiter is Delimiter, or the
+// one of --delimiter if it is not set.
+type CsvOutputWriter struct {
+	Delimiter rune
+}
+
+func (c *CsvOutputWriter) Render(out io.Writer, w *WeatherResponse, opts RenderOptions) error {
+	return c.print(out, []Record{WeatherRecord(w, opts.Lookup, opts.Response, opts)}, opts)
+}
+
+func (c *CsvOutputWriter) RenderForecast(out io.Writer, f *ForecastResponse, opts RenderOptions) error {
+	return c.print(out, ForecastRecords(f, opts.Lookup, opts.Response, opts), opts)
+}
+
+func (c *CsvOutputWriter) RenderDaily(out io.Writer, d *DailyForecast, opts RenderOptions) error {
+	return c.print(out, DailyRecords(d, opts.Lookup, opts.Response, opts), opts)
+}
+
+// RenderLocations prints the rows of all locations under one header, a failed
+// location is a row with the error columns.
+func (c *CsvOutputWriter) RenderLocations(out io.Writer, results []LocationResult, opts RenderOptions) error {
+	return c.print(out, LocationRecords(results, opts), opts)
+}
+
+// RenderError logs the error, see logError, so the output stays a table.
+func (c *CsvOutputWriter) RenderError(out io.Writer, err error, opts RenderOptions) error {
+	return logError(out, err)
+}
+
+func (c *CsvOutputWriter) print(out io.Writer, records []Record, opts RenderOptions) error {
+	writer := csv.NewWriter(out)
+	switch {
+	case c.Delimiter != 0:
+		writer.Comma = c.Delimiter
+	case opts.Delimiter != 0:
+		writer.Comma = opts.Delimiter
+	}
+
+	columns := RecordColumns()
+	if err := writer.Write(columns); err != nil {
+		return err
+	}
+	for _, record := range records {
+		tree, err := orderedJSON(record)
+		if err != nil {
+			return err
+		}
+		values := map[string]string{}
+		flatten(values, "", tree)
+
+		row := make([]string, len(columns))
+		for i, column := range columns {
+			row[i] = values[column]
+		}
+		if err := writer.Write(row); err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
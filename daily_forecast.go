@@ -0,0 +1,123 @@
+package main
+
+import (
+	"io"
+	"time"
+
+	"github.com/belovai/goweather/goopenweathermapapi"
+)
+
+type DailyForecast struct {
+	City goopenweathermapapi.ForecastCity
+	Days []DailySummary
+}
+
+type DailySummary struct {
+	Date        string
+	TempMin     float64
+	TempMax     float64
+	Main        string
+	Description string
+	Rain        float64
+	Snow        float64
+	WindMax     float64
+	GustMax     float64
+	PopMax      float64
+	Slots       int
+}
+
+// NewDailyForecast rolls the 3 hour forecast slots up into per-day summaries.
+// Days are split by the city's own timezone, not by the local time of the machine.
+func NewDailyForecast(f *ForecastResponse) *DailyForecast {
+	zone := time.FixedZone(f.City.Name, f.City.Timezone)
+	daily := &DailyForecast{City: f.City, Days: []DailySummary{}}
+
+	var mains []*counter
+	var descriptions []map[string]*counter
+
+	for _, item := range f.List {
+		date := time.Unix(item.Dt, 0).In(zone).Format("2006-01-02")
+
+		last := len(daily.Days) - 1
+		if last < 0 || daily.Days[last].Date != date {
+			daily.Days = append(daily.Days, DailySummary{
+				Date:    date,
+				TempMin: item.Main.TempMin,
+				TempMax: item.Main.TempMax,
+			})
+			mains = append(mains, &counter{})
+			descriptions = append(descriptions, map[string]*counter{})
+			last++
+		}
+
+		day := &daily.Days[last]
+		day.Slots++
+		if item.Main.TempMin < day.TempMin {
+			day.TempMin = item.Main.TempMin
+		}
+		if item.Main.TempMax > day.TempMax {
+			day.TempMax = item.Main.TempMax
+		}
+		rain, _ := item.Rain.Volume("3h")
+		day.Rain += rain
+		snow, _ := item.Snow.Volume("3h")
+		day.Snow += snow
+		if item.Wind.Speed > day.WindMax {
+			day.WindMax = item.Wind.Speed
+		}
+		if gust := item.Wind.GustSpeed(); gust > day.GustMax {
+			day.GustMax = gust
+		}
+		if item.Pop > day.PopMax {
+			day.PopMax = item.Pop
+		}
+
+		for _, weather := range item.Weather {
+			mains[last].add(weather.Main)
+			if descriptions[last][weather.Main] == nil {
+				descriptions[last][weather.Main] = &counter{}
+			}
+			descriptions[last][weather.Main].add(weather.Description)
+		}
+	}
+
+	for i := range daily.Days {
+		daily.Days[i].Main = mains[i].dominant()
+		if c := descriptions[i][daily.Days[i].Main]; c != nil {
+			daily.Days[i].Description = c.dominant()
+		}
+	}
+
+	return daily
+}
+
+func (d *DailyForecast) Render(out io.Writer, outputWriter OutputWriterInterface, opts RenderOptions) error {
+	return outputWriter.RenderDaily(out, d, opts)
+}
+
+// counter counts occurrences and remembers the order of the first appearance,
+// so ties are resolved by the earlier value.
+type counter struct {
+	counts map[string]int
+	order  []string
+}
+
+func (c *counter) add(key string) {
+	if c.counts == nil {
+		c.counts = map[string]int{}
+	}
+	if c.counts[key] == 0 {
+		c.order = append(c.order, key)
+	}
+	c.counts[key]++
+}
+
+func (c *counter) dominant() string {
+	best := ""
+	for _, key := range c.order {
+		if c.counts[key] > c.counts[best] {
+			best = key
+		}
+	}
+	return best
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/belovai/goweather/goopenweathermapapi"
+)
+
+func TestNewDailyForecast(t *testing.T) {
+	forecast := &ForecastResponse{
+		City: goopenweathermapapi.ForecastCity{Name: "Auckland", Timezone: 13 * 3600},
+		List: []goopenweathermapapi.ForecastItem{
+			// 2018-11-03 09:00 UTC is already 2018-11-03 22:00 in Auckland
+			{Dt: 1541235600, Main: goopenweathermapapi.Main{TempMin: 10, TempMax: 12}, Wind: goopenweathermapapi.Wind{Speed: 3, Gust: float(5)}, Pop: 0.1, Weather: []goopenweathermapapi.Weather{{Main: "Clouds", Description: "overcast clouds"}}},
+			// 2018-11-03 12:00 UTC is 2018-11-04 01:00 in Auckland
+			{Dt: 1541246400, Main: goopenweathermapapi.Main{TempMin: 8, TempMax: 9}, Wind: goopenweathermapapi.Wind{Speed: 6, Gust: float(9)}, Pop: 0.8, Rain: &goopenweathermapapi.Precipitation{ThreeHours: float(1.5)}, Weather: []goopenweathermapapi.Weather{{Main: "Rain", Description: "light rain"}}},
+			{Dt: 1541257200, Main: goopenweathermapapi.Main{TempMin: 7, TempMax: 11}, Wind: goopenweathermapapi.Wind{Speed: 4, Gust: float(12)}, Pop: 0.6, Rain: &goopenweathermapapi.Precipitation{ThreeHours: float(0.25)}, Weather: []goopenweathermapapi.Weather{{Main: "Rain", Description: "moderate rain"}}},
+			{Dt: 1541268000, Main: goopenweathermapapi.Main{TempMin: 9, TempMax: 10}, Wind: goopenweathermapapi.Wind{Speed: 2}, Weather: []goopenweathermapapi.Weather{{Main: "Rain", Description: "light rain"}}},
+		},
+	}
+
+	daily := NewDailyForecast(forecast)
+
+	if len(daily.Days) != 2 {
+		t.Fatal("Error in number of days")
+	}
+
+	if daily.Days[0].Date != "2018-11-03" || daily.Days[1].Date != "2018-11-04" {
+		t.Error("Error in grouping by city timezone")
+	}
+
+	day := daily.Days[1]
+	if day.TempMin != 7 || day.TempMax != 11 {
+		t.Error("Error in min/max temperature")
+	}
+
+	if day.Rain != 1.75 {
+		t.Error("Error in total rain")
+	}
+
+	if day.WindMax != 6 || day.GustMax != 12 {
+		t.Error("Error in max wind")
+	}
+
+	if day.PopMax != 0.8 {
+		t.Error("Error in max pop")
+	}
+
+	if day.Main != "Rain" || day.Description != "light rain" {
+		t.Error("Error in dominant condition")
+	}
+
+	if day.Slots != 3 {
+		t.Error("Error in slots")
+	}
+}
+
+func float(f float64) *float64 {
+	return &f
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// AmbiguousCityError is returned when a city name matches several cities and
+// none of them was picked.
+type AmbiguousCityError struct {
+	City       string
+	Candidates []City
+}
+
+func (e *AmbiguousCityError) Error() string {
+	lines := []string{fmt.Sprintf("ambiguous city %s, pick one with --pick N or use --id:", e.City)}
+	for i, candidate := range e.Candidates {
+		lines = append(lines, fmt.Sprintf("  %d. %s", i+1, candidate))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Disambiguate asks the find endpoint for the cities of the city name lookups.
+// A name of several cities is replaced with the city ID of the one picked by
+// PickCity. A name with a country code, e.g. London,gb, is taken as unique and
+// is not searched. The search is skipped in cache-only mode, and if it fails
+// the name is used as it is.
+func Disambiguate(ctx context.Context, targets []Target) ([]Target, error) {
+	if *CacheOnly {
+		return targets, nil
+	}
+
+	client, err := NewSearchClient()
+	if err != nil {
+		return nil, err
+	}
+
+	var index map[int]City
+	resolved := make([]Target, 0, len(targets))
+	for _, target := range targets {
+		if target.Lookup.Kind != LookupCity || hasCountry(target.Lookup.City) {
+			resolved = append(resolved, target)
+			continue
+		}
+
+		candidates := findCandidates(ctx, client, target.Lookup.City)
+		if len(candidates) > 1 {
+			if index == nil {
+				index = loadStates()
+			}
+			for i := range candidates {
+				candidates[i].State = index[candidates[i].ID].State
+			}
+
+			city, err := PickCity(target.Lookup.City, candidates)
+			if err != nil {
+				return nil, err
+			}
+			target.Lookup = Lookup{Kind: LookupID, ID: city.ID}
+		}
+		resolved = append(resolved, target)
+	}
+	return resolved, nil
+}
+
+// hasCountry reports whether the city name has a country code, like in
+// London,gb.
+func hasCountry(name string) bool {
+	return strings.Contains(name, ",")
+}
+
+// findCandidates returns the cities of the name, without duplicates.
+func findCandidates(ctx context.Context, client *CachedClient, name string) []City {
+	result, err := client.Find(ctx, name)
+	if err != nil {
+		return nil
+	}
+
+	var candidates []City
+	seen := map[int]bool{}
+	for _, w := range result.List {
+		if seen[w.Id] {
+			continue
+		}
+		seen[w.Id] = true
+		candidates = append(candidates, City{
+			ID:      w.Id,
+			Name:    w.Name,
+			Country: w.Sys.Country,
+			Lat:     w.Coord.Lat,
+			Lon:     w.Coord.Lon,
+		})
+	}
+	return candidates
+}
+
+// loadStates returns the cities of the city index by ID, the find endpoint
+// does not report the state. It is empty if there is no index.
+func loadStates() map[int]City {
+	index := map[int]City{}
+	path, err := DefaultCityIndexPath()
+	if err != nil {
+		return index
+	}
+	cities, err := LoadCityIndex(path)
+	if err != nil {
+		return index
+	}
+	for _, city := range cities {
+		index[city.ID] = city
+	}
+	return index
+}
+
+// PickCity chooses one of the candidates of the city name by --pick or by
+// asking on the terminal. Without a terminal it returns an *AmbiguousCityError
+// instead of guessing.
+func PickCity(name string, candidates []City) (City, error) {
+	if *Pick > 0 {
+		if *Pick > len(candidates) {
+			return City{}, &UsageError{Message: fmt.Sprintf("invalid pick: %d, %s matches %d cities", *Pick, name, len(candidates))}
+		}
+		return candidates[*Pick-1], nil
+	}
+
+	ambiguous := &AmbiguousCityError{City: name, Candidates: candidates}
+	if *Format != "pretty" || !isTerminal(os.Stdin) || !isTerminal(os.Stderr) {
+		return City{}, ambiguous
+	}
+
+	fmt.Fprintf(os.Stderr, "%s matches several cities:\n", name)
+	for i, candidate := range candidates {
+		fmt.Fprintf(os.Stderr, "  %d. %s\n", i+1, candidate)
+	}
+	reader := bufio.NewReader(os.Stdin)
+	for {
+		fmt.Fprintf(os.Stderr, "Pick a city [1-%d]: ", len(candidates))
+		line, err := reader.ReadString('\n')
+		if n, convErr := strconv.Atoi(strings.TrimSpace(line)); convErr == nil && n >= 1 && n <= len(candidates) {
+			return candidates[n-1], nil
+		}
+		if err != nil {
+			return City{}, ambiguous
+		}
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/belovai/goweather/goopenweathermapapi"
+)
+
+const findJson = `{"message":"accurate","cod":"200","count":3,"list":[
+{"id":4951788,"name":"Springfield","coord":{"lat":42.1015,"lon":-72.5898},"main":{"temp":10},"sys":{"country":"US"}},
+{"id":4409896,"name":"Springfield","coord":{"lat":37.2153,"lon":-93.2982},"main":{"temp":12},"sys":{"country":"US"}},
+{"id":4409896,"name":"Springfield","coord":{"lat":37.2153,"lon":-93.2982},"main":{"temp":12},"sys":{"country":"US"}}
+]}`
+
+func TestFindCandidates(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Path != "/find" || r.URL.Query().Get("q") != "Springfield" || r.URL.Query().Get("type") != "accurate" {
+			t.Error("Error in find request: " + r.URL.String())
+		}
+		w.Write([]byte(findJson))
+	}))
+	defer server.Close()
+
+	client := &CachedClient{
+		Client: goopenweathermapapi.NewClient("appid", goopenweathermapapi.WithBaseURL(server.URL)),
+		Cache:  &Cache{Dir: t.TempDir(), TTL: time.Hour},
+	}
+
+	candidates := findCandidates(context.Background(), client, "Springfield")
+	if len(candidates) != 2 || candidates[0].ID != 4951788 || candidates[1].Country != "US" || candidates[1].Lat != 37.2153 {
+		t.Fatal("Error in candidates")
+	}
+
+	findCandidates(context.Background(), client, "Springfield")
+	if requests != 1 {
+		t.Error("Error in cached candidates")
+	}
+
+	if hasCountry("Springfield") || !hasCountry("Springfield,us") {
+		t.Error("Error in country code")
+	}
+
+	pick, format := 0, "json"
+	Pick, Format = &pick, &format
+
+	_, err := PickCity("Springfield", candidates)
+	if ExitCode(err) != ExitAmbiguousCity || !strings.Contains(err.Error(), "  2. 4409896 Springfield US (37.22,-93.30)") {
+		t.Error("Error in ambiguous city")
+	}
+
+	pick = 3
+	if _, err := PickCity("Springfield", candidates); ExitCode(err) != ExitUsage {
+		t.Error("Error in pick out of range")
+	}
+
+	pick = 1
+	if city, err := PickCity("Springfield", candidates); err != nil || city.ID != 4951788 {
+		t.Error("Error in picked city")
+	}
+}
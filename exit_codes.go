@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+
+	"github.com/belovai/goweather/goopenweathermapapi"
+)
+
+// Exit codes of goweather, they are documented in the README.
+const (
+	ExitOK            = 0
+	ExitError         = 1
+	ExitUsage         = 2
+	ExitInvalidAPIKey = 3
+	ExitCityNotFound  = 4
+	ExitRateLimited   = 5
+	ExitNetwork       = 6
+	ExitDecode        = 7
+	ExitAPI           = 8
+	ExitNoCachedData  = 9
+	ExitAmbiguousCity = 10
+)
+
+var exitCategories = map[int]string{
+	ExitError:         "error",
+	ExitUsage:         "usage",
+	ExitInvalidAPIKey: "invalid_api_key",
+	ExitCityNotFound:  "city_not_found",
+	ExitRateLimited:   "rate_limited",
+	ExitNetwork:       "network",
+	ExitDecode:        "decode",
+	ExitAPI:           "api",
+	ExitNoCachedData:  "no_cached_data",
+	ExitAmbiguousCity: "ambiguous_city",
+}
+
+// UsageError is returned for invalid options.
+type UsageError struct {
+	Message string
+}
+
+func (e *UsageError) Error() string {
+	return e.Message
+}
+
+// ReportedError is an error which was already rendered, e.g. a failed location
+// of a multi-location run. Exit only sets its exit code.
+type ReportedError struct {
+	Err error
+}
+
+func (e *ReportedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ReportedError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode maps an error to the exit code of the program.
+func ExitCode(err error) int {
+	var usageError *UsageError
+	var ambiguousCity *AmbiguousCityError
+	switch {
+	case err == nil:
+		return ExitOK
+	case errors.As(err, &usageError), errors.Is(err, goopenweathermapapi.ErrRequest):
+		return ExitUsage
+	case errors.As(err, &ambiguousCity):
+		return ExitAmbiguousCity
+	case errors.Is(err, goopenweathermapapi.ErrInvalidAPIKey):
+		return ExitInvalidAPIKey
+	case errors.Is(err, goopenweathermapapi.ErrCityNotFound):
+		return ExitCityNotFound
+	case errors.Is(err, goopenweathermapapi.ErrRateLimited):
+		return ExitRateLimited
+	case errors.Is(err, goopenweathermapapi.ErrNetwork):
+		return ExitNetwork
+	case errors.Is(err, goopenweathermapapi.ErrDecode):
+		return ExitDecode
+	case errors.Is(err, goopenweathermapapi.ErrAPI):
+		return ExitAPI
+	case errors.Is(err, ErrNoCachedData):
+		return ExitNoCachedData
+	}
+	return ExitError
+}
+
+// ErrorCategory is the machine-readable name of the exit code of the error.
+func ErrorCategory(err error) string {
+	return exitCategories[ExitCode(err)]
+}
+
+// HTTPStatus returns the status code of the api response or zero if the
+// error did not come from the api.
+func HTTPStatus(err error) int {
+	var apiError *goopenweathermapapi.Error
+	if errors.As(err, &apiError) {
+		return apiError.StatusCode
+	}
+	return 0
+}
+
+// Retryable reports whether the same request may succeed later.
+func Retryable(err error) bool {
+	switch ExitCode(err) {
+	case ExitRateLimited, ExitNetwork:
+		return true
+	case ExitAPI:
+		return HTTPStatus(err) >= http.StatusInternalServerError
+	}
+	return false
+}
+
+// NotFoundError is the city not found error of the lookup. Err is the error
+// of the api, the message of the api is left out since it only repeats that
+// the city was not found.
+type NotFoundError struct {
+	Lookup Lookup
+	Err    error
+}
+
+func (e *NotFoundError) Error() string {
+	message := fmt.Sprintf("%s: not found", e.Lookup.Target())
+	if status := HTTPStatus(e.Err); status != 0 {
+		message += fmt.Sprintf(" (HTTP %d)", status)
+	}
+	return message
+}
+
+func (e *NotFoundError) Unwrap() error {
+	return e.Err
+}
+
+// Exit renders the error with the selected output writer, starts the refresh
+// of the cache entries which were queued in cache-only mode and exits with
+// its exit code.
+func Exit(err error) {
+	refreshQueue.StartRefresh()
+	var reported *ReportedError
+	if !errors.As(err, &reported) {
+		RenderError(err)
+	}
+	os.Exit(ExitCode(err))
+}
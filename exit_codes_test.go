@@ -0,0 +1,64 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/belovai/goweather/goopenweathermapapi"
+)
+
+func TestExitCode(t *testing.T) {
+	if ExitCode(nil) != ExitOK {
+		t.Error("Error in nil error")
+	}
+
+	if ExitCode(&goopenweathermapapi.Error{Kind: goopenweathermapapi.ErrInvalidAPIKey, StatusCode: 401}) != ExitInvalidAPIKey {
+		t.Error("Error in invalid API key")
+	}
+
+	if ExitCode(&goopenweathermapapi.Error{Kind: goopenweathermapapi.ErrCityNotFound, StatusCode: 404}) != ExitCityNotFound {
+		t.Error("Error in city not found")
+	}
+
+	notFound := &NotFoundError{Lookup: Lookup{Kind: LookupCity, City: "Nowhere"}, Err: &goopenweathermapapi.Error{Kind: goopenweathermapapi.ErrCityNotFound, StatusCode: 404}}
+	if ExitCode(notFound) != ExitCityNotFound || notFound.Error() != `city "Nowhere": not found (HTTP 404)` {
+		t.Error("Error in not found message: " + notFound.Error())
+	}
+
+	wrapped := fmt.Errorf("current weather: %w", &goopenweathermapapi.Error{Kind: goopenweathermapapi.ErrNetwork, Err: errors.New("timeout")})
+	if ExitCode(wrapped) != ExitNetwork {
+		t.Error("Error in wrapped network failure")
+	}
+
+	if ExitCode(&goopenweathermapapi.Error{Kind: goopenweathermapapi.ErrDecode, Err: errors.New("unexpected EOF")}) != ExitDecode {
+		t.Error("Error in decode failure")
+	}
+
+	if ExitCode(errors.New("something else")) != ExitError {
+		t.Error("Error in unknown error")
+	}
+}
+
+func TestRetryable(t *testing.T) {
+	if !Retryable(&goopenweathermapapi.Error{Kind: goopenweathermapapi.ErrRateLimited, StatusCode: 429}) {
+		t.Error("Error in rate limited")
+	}
+
+	if !Retryable(&goopenweathermapapi.Error{Kind: goopenweathermapapi.ErrAPI, StatusCode: 502}) {
+		t.Error("Error in server error")
+	}
+
+	if Retryable(&goopenweathermapapi.Error{Kind: goopenweathermapapi.ErrInvalidAPIKey, StatusCode: 401}) {
+		t.Error("Error in invalid API key")
+	}
+
+	invalidRequest := &goopenweathermapapi.Error{Kind: goopenweathermapapi.ErrRequest, Err: errors.New("missing ']' in host")}
+	if Retryable(invalidRequest) || ExitCode(invalidRequest) != ExitUsage {
+		t.Error("Error in invalid request")
+	}
+
+	if ErrorCategory(&UsageError{Message: "missing city"}) != "usage" {
+		t.Error("Error in usage category")
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+)
+
+// foldTable maps the accented latin letters to their ascii letters.
+var foldTable = map[rune]string{}
+
+func init() {
+	for ascii, accented := range map[string]string{
+		"a":  "àáâãäåāăąǎǟǡǻȁȃȧạảấầẩẫậắằẳẵặ",
+		"c":  "çćĉċč",
+		"d":  "ďđḍḏð",
+		"e":  "èéêëēĕėęěȅȇȩẹẻẽếềểễệ",
+		"g":  "ĝğġģǧ",
+		"h":  "ĥħḥḩḫ",
+		"i":  "ìíîïĩīĭįıǐȉȋịỉ",
+		"j":  "ĵ",
+		"k":  "ķǩḳ",
+		"l":  "ĺļľŀłḷ",
+		"n":  "ñńņňŉṅṇ",
+		"o":  "òóôõöøōŏőơǒǫȍȏȯọỏốồổỗộớờởỡợ",
+		"r":  "ŕŗřȑȓṛ",
+		"s":  "śŝşšșṣ",
+		"t":  "ţťŧțṭ",
+		"u":  "ùúûüũūŭůűųưǔǖǘǚǜȕȗụủứừửữự",
+		"w":  "ŵẁẃẅ",
+		"y":  "ýÿŷỳỵỷỹ",
+		"z":  "źżžẓẕ",
+		"ae": "æǽ",
+		"oe": "œ",
+		"ss": "ß",
+		"th": "þ",
+	} {
+		for _, r := range accented {
+			foldTable[r] = ascii
+		}
+	}
+}
+
+// Fold normalizes a place name for searching: it is lower cased, the accents
+// are removed and everything but letters and digits is a single space, e.g.
+// "São Tomé" is "sao tome".
+func Fold(s string) string {
+	var b strings.Builder
+	space := false
+	for _, r := range strings.ToLower(s) {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		ascii := foldTable[r]
+		if ascii == "" && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			space = b.Len() > 0
+			continue
+		}
+
+		if space {
+			b.WriteByte(' ')
+			space = false
+		}
+		if ascii != "" {
+			b.WriteString(ascii)
+		} else {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Distance is the number of inserted, deleted, replaced and swapped letters
+// between a and b. It gives up and returns limit+1 once the distance is over limit.
+func Distance(a, b string, limit int) int {
+	ra, rb := []rune(a), []rune(b)
+	if diff := len(ra) - len(rb); diff > limit || -diff > limit {
+		return limit + 1
+	}
+
+	// Three rows of the optimal string alignment matrix.
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	row := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		row[0] = i
+		best := row[0]
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			row[j] = minInt(prev[j]+1, row[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				row[j] = minInt(row[j], prev2[j-2]+1)
+			}
+			if row[j] < best {
+				best = row[j]
+			}
+		}
+		if best > limit {
+			return limit + 1
+		}
+		prev2, prev, row = prev, row, prev2
+	}
+
+	if prev[len(rb)] > limit {
+		return limit + 1
+	}
+	return prev[len(rb)]
+}
+
+func minInt(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
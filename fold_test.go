@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestFold(t *testing.T) {
+	if Fold("São Tomé") != "sao tome" {
+		t.Error("Error in accents")
+	}
+
+	if Fold("  Val-d'Or ") != "val d or" {
+		t.Error("Error in separators")
+	}
+
+	if Fold("Ḩeşār-e Sefīd") != "hesar e sefid" {
+		t.Error("Error in extended latin")
+	}
+
+	if Fold("Großenhain") != "grossenhain" {
+		t.Error("Error in sharp s")
+	}
+
+	if Fold("Москва") != "москва" {
+		t.Error("Error in non-latin letters")
+	}
+}
+
+func TestDistance(t *testing.T) {
+	if Distance("london", "london", 2) != 0 {
+		t.Error("Error in equal strings")
+	}
+
+	if Distance("lodnon", "london", 2) != 1 {
+		t.Error("Error in swapped letters")
+	}
+
+	if Distance("londn", "london", 2) != 1 {
+		t.Error("Error in missing letter")
+	}
+
+	if Distance("paris", "london", 2) != 3 {
+		t.Error("Error in limit")
+	}
+}
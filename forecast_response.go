@@ -0,0 +1,6 @@
+package main
+
+import "github.com/belovai/goweather/goopenweathermapapi"
+
+// ForecastResponse is the 5 day / 3 hour forecast as decoded by the api client.
+type ForecastResponse = goopenweathermapapi.Forecast
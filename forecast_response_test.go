@@ -0,0 +1,32 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const forecastJson = `{"cod":"200","message":0,"cnt":1,"list":[{"dt":1541246400,"main":{"temp":11.4,"temp_min":10.2,"temp_max":11.4,"pressure":1021,"humidity":81},"weather":[{"id":500,"main":"Rain","description":"light rain","icon":"10d"}],"clouds":{"all":92},"wind":{"speed":4.1,"deg":201},"pop":0.35,"rain":{"3h":0.25},"sys":{"pod":"d"},"dt_txt":"2018-11-03 12:00:00"}],"city":{"id":2643743,"name":"London","coord":{"lat":51.5073,"lon":-0.1277},"country":"GB","population":1000000,"timezone":0}}`
+
+func TestDecodeForecast(t *testing.T) {
+	var forecast ForecastResponse
+	if err := json.NewDecoder(strings.NewReader(forecastJson)).Decode(&forecast); err != nil {
+		t.Fatal(err)
+	}
+
+	if forecast.City.Name != "London" {
+		t.Error("Error in city name")
+	}
+
+	if len(forecast.List) != 1 {
+		t.Fatal("Error in list length")
+	}
+
+	if forecast.List[0].Pop != 0.35 {
+		t.Error("Error in pop")
+	}
+
+	if rain, ok := forecast.List[0].Rain.Volume("3h"); !ok || rain != 0.25 {
+		t.Error("Error in rain")
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// OutputWriterInterface renders the results of the commands in an output
+// format. The writers write to out and get everything else from the options,
+// they must not read the option globals.
+type OutputWriterInterface interface {
+	Render(out io.Writer, w *WeatherResponse, opts RenderOptions) error
+	RenderForecast(out io.Writer, f *ForecastResponse, opts RenderOptions) error
+	RenderDaily(out io.Writer, d *DailyForecast, opts RenderOptions) error
+	RenderLocations(out io.Writer, results []LocationResult, opts RenderOptions) error
+	RenderError(out io.Writer, err error, opts RenderOptions) error
+}
+
+// BodyWriter is implemented by the formats which print the response bodies of
+// the API as they are. RenderBody gets the bodies of the failed requests too,
+// which could not be decoded or are errors of the API, from opts.Response.
+type BodyWriter interface {
+	RenderBody(out io.Writer, opts RenderOptions) error
+}
+
+// RenderOptions are the settings of the output.
+type RenderOptions struct {
+	// Units are metric or imperial.
+	Units string
+	// Timezone of the times, nil is the timezone of the city.
+	Timezone *time.Location
+	// Command is current, forecast or daily.
+	Command string
+	// Lookup and Response are the lookup and the response of Render,
+	// RenderForecast and RenderDaily.
+	Lookup   Lookup
+	Response *Response
+	// Template is the text of --template or --template-file.
+	Template string
+	// JsonSchema is the version of the JSON output, see JsonSchemaVersion.
+	JsonSchema int
+	// RawIndent indents the response bodies of the raw format.
+	RawIndent bool
+	// Delimiter is the delimiter of the csv format, zero is a comma.
+	Delimiter rune
+}
+
+// NewRenderOptions returns the render options of the command line.
+func NewRenderOptions(command string) (RenderOptions, error) {
+	opts := RenderOptions{Units: *Units, Command: command, JsonSchema: jsonSchema(), RawIndent: *RawIndent}
+	if opts.Command == "" {
+		opts.Command = "current"
+	}
+	if *Timezone != "" {
+		zone, err := time.LoadLocation(*Timezone)
+		if err != nil {
+			return opts, &UsageError{Message: fmt.Sprintf("invalid timezone: %s", *Timezone)}
+		}
+		opts.Timezone = zone
+	}
+
+	if *Delimiter != "" {
+		delimiter := []rune(*Delimiter)
+		if len(delimiter) != 1 || strings.ContainsRune("\"\r\n\uFFFD", delimiter[0]) {
+			return opts, &UsageError{Message: fmt.Sprintf("invalid delimiter: %q, use one character", *Delimiter)}
+		}
+		opts.Delimiter = delimiter[0]
+	}
+
+	// The template is read and parsed only for the template format, e.g. a
+	// GOWEATHER_TEMPLATE_FILE of another format may not exist.
+	if *Format == "template" {
+		template, err := LoadTemplate(*Template, *TemplateFile)
+		if err != nil {
+			return opts, err
+		}
+		opts.Template = template
+		if opts.Template == "" {
+			return opts, &UsageError{Message: "--format=template needs --template or --template-file"}
+		}
+		if _, err := ParseTemplate(opts.Template, opts); err != nil {
+			return opts, err
+		}
+	}
+	return opts, nil
+}
+
+// jsonSchema returns the version of --json-schema.
+func jsonSchema() int {
+	if JsonSchema != nil && *JsonSchema == "1" {
+		return 1
+	}
+	return JsonSchemaVersion
+}
+
+// UnitSigns returns the temperature and the speed sign of the units.
+func (o RenderOptions) UnitSigns() (tempSign, speedSign string) {
+	if o.Units == "imperial" {
+		return "°F", "mph"
+	}
+	return "°C", "m/s"
+}
+
+// In returns the timezone of the times: Timezone or the timezone of the city.
+func (o RenderOptions) In(city *time.Location) *time.Location {
+	if o.Timezone != nil {
+		return o.Timezone
+	}
+	return city
+}
+
+// OutputFormat is a registered output format of --format.
+type OutputFormat struct {
+	Name        string
+	Description string
+	New         func() OutputWriterInterface
+	// StdoutErrors renders the errors to stdout where the results go, for
+	// machine readable formats. Otherwise they go to stderr.
+	StdoutErrors bool
+}
+
+var outputFormats = map[string]OutputFormat{}
+
+// RegisterFormat adds an output format, usually from an init function. It
+// panics if the name is already registered.
+func RegisterFormat(format OutputFormat) {
+	if _, ok := outputFormats[format.Name]; ok {
+		panic("goweather: format registered twice: " + format.Name)
+	}
+	outputFormats[format.Name] = format
+}
+
+// FormatNames returns the names of the registered formats in order.
+func FormatNames() []string {
+	names := make([]string, 0, len(outputFormats))
+	for name := range outputFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewOutputWriter returns the writer of a registered format.
+func NewOutputWriter(name string) (OutputWriterInterface, error) {
+	format, ok := outputFormats[name]
+	if !ok {
+		return nil, &UsageError{Message: fmt.Sprintf("unknown format: %s, use one of: %s", name, strings.Join(FormatNames(), ", "))}
+	}
+	return format.New(), nil
+}
+
+// RenderError renders the error with the writer of --format, or of pretty if
+// the format is not known yet.
+func RenderError(err error) {
+	format, ok := outputFormats["pretty"]
+	if Format != nil {
+		if selected, found := outputFormats[*Format]; found {
+			format, ok = selected, true
+		}
+	}
+	if !ok {
+		log.Println(err)
+		return
+	}
+
+	out := os.Stderr
+	if format.StdoutErrors {
+		out = os.Stdout
+	}
+	opts := RenderOptions{JsonSchema: jsonSchema()}
+	if Units != nil {
+		opts.Units = *Units
+	}
+	if renderErr := format.New().RenderError(out, err, opts); renderErr != nil {
+		log.Println(err)
+	}
+}
+
+// dataFormats print the settings and the lists of config show, cities and
+// geocode, which are not weather. pretty is printed by the commands.
+var dataFormats = map[string]func(out io.Writer, v interface{}) error{
+	"json": (&JsonOutputWriter{}).print,
+	"yaml": (&YamlOutputWriter{}).print,
+	"toml": (&TomlOutputWriter{}).print,
+}
+
+// DataPrinter returns the printer of --format for a command which is not
+// weather, or nil for pretty. The other formats are usage errors.
+func DataPrinter(command string) (func(out io.Writer, v interface{}) error, error) {
+	if *Format == "pretty" {
+		return nil, nil
+	}
+	if printer, ok := dataFormats[*Format]; ok {
+		return printer, nil
+	}
+	names := []string{"pretty"}
+	for name := range dataFormats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return nil, &UsageError{Message: fmt.Sprintf("--format=%s can not be used with %s, use one of: %s", *Format, command, strings.Join(names, ", "))}
+}
+
+// logError writes the error like the log package does to stderr, it is the
+// RenderError of the formats which print text instead of data.
+func logError(out io.Writer, err error) error {
+	return log.New(out, "", log.LstdFlags).Output(2, err.Error())
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// failingWriter fails every write.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestOutputFormats(t *testing.T) {
+	if names := strings.Join(FormatNames(), ","); !strings.Contains(names, "json") || !strings.Contains(names, "pretty") {
+		t.Error("Error in format names: " + names)
+	}
+
+	if _, err := NewOutputWriter("xml"); ExitCode(err) != ExitUsage {
+		t.Error("Error in unknown format")
+	}
+
+	var weather WeatherResponse
+	if err := json.Unmarshal([]byte(weatherJson), &weather); err != nil {
+		t.Fatal(err)
+	}
+
+	zone, err := time.LoadLocation("Europe/Budapest")
+	if err != nil {
+		t.Skip(err)
+	}
+	opts := RenderOptions{
+		Units:    "imperial",
+		Timezone: zone,
+		Command:  "current",
+		Lookup:   Lookup{Kind: LookupCity, City: "London,gb"},
+	}
+
+	writer, _ := NewOutputWriter("pretty")
+	var out bytes.Buffer
+	if err := writer.Render(&out, &weather, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "12°F (feels like 11°F)") || !strings.Contains(out.String(), "Sunrise: 07:53\n") {
+		t.Error("Error in pretty output: " + out.String())
+	}
+
+	if err := writer.Render(failingWriter{}, &weather, opts); err == nil {
+		t.Error("Error in pretty write error")
+	}
+
+	writer, _ = NewOutputWriter("json")
+	out.Reset()
+	if err := writer.Render(&out, &weather, opts); err != nil || !strings.Contains(out.String(), `"temp":12.3,`) {
+		t.Error("Error in json output: " + out.String())
+	}
+
+	opts.JsonSchema = 1
+	out.Reset()
+	if err := writer.Render(&out, &weather, opts); err != nil || !strings.Contains(out.String(), `"temp":"12°F"`) {
+		t.Error("Error in json schema 1 output: " + out.String())
+	}
+
+	if err := writer.Render(failingWriter{}, &weather, opts); err == nil {
+		t.Error("Error in json write error")
+	}
+}
+
+func TestPrettyLocations(t *testing.T) {
+	var weather WeatherResponse
+	if err := json.Unmarshal([]byte(weatherJson), &weather); err != nil {
+		t.Fatal(err)
+	}
+
+	results := []LocationResult{
+		{Target: Target{Location: "home"}, Weather: &weather},
+		{Target: Target{Location: "a much longer name"}, Err: &UsageError{Message: "failed"}},
+		{Target: Target{Location: "cabin"}, Weather: &weather},
+	}
+	writer, _ := NewOutputWriter("pretty")
+	var out bytes.Buffer
+	if err := writer.RenderLocations(&out, results, RenderOptions{Command: "current"}); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(out.String(), "\n")
+	column := strings.Index(lines[0], "Weather")
+	for _, line := range lines[1:4] {
+		if strings.Index(line, "unknown") != column && strings.Index(line, "error") != column {
+			t.Error("Error in alignment of: " + line)
+		}
+	}
+	if lines[4] != "Error: a much longer name: failed" {
+		t.Error("Error in error list: " + lines[4])
+	}
+}
+
+func TestDataPrinter(t *testing.T) {
+	format := "pretty"
+	Format = &format
+	if printer, err := DataPrinter("geocode"); printer != nil || err != nil {
+		t.Error("Error in pretty data format")
+	}
+
+	format = "yaml"
+	printer, err := DataPrinter("geocode")
+	var out bytes.Buffer
+	if err != nil || printer(&out, map[string]interface{}{"cities": 2}) != nil || out.String() != "cities: 2\n" {
+		t.Error("Error in yaml data format: " + out.String())
+	}
+
+	format = "csv"
+	if _, err := DataPrinter("config show"); ExitCode(err) != ExitUsage || !strings.HasSuffix(err.Error(), "use one of: json, pretty, toml, yaml") {
+		t.Error("Error in unsupported data format")
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/belovai/goweather/goopenweathermapapi"
+)
+
+// geocodeLimit is the number of places asked from the geocoding api, it is
+// the maximum of the api.
+const geocodeLimit = 5
+
+// PlaceName formats the place as "name, state, country" with the name in the
+// language lang, e.g. of --lang.
+func PlaceName(place goopenweathermapapi.Place, lang string) string {
+	parts := []string{place.LocalName(lang)}
+	for _, part := range []string{place.State, place.Country} {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// ResolvePlaces sets the place name of the coordinates lookups by reverse
+// geocoding, the weather api reports only the nearest station. Airports have
+// their name already. It is best effort: if geocoding fails the lookup has no
+// place name. It is skipped with --no-cache, which would make the request on
+// every run. The names are in the language lang.
+func ResolvePlaces(ctx context.Context, targets []Target, lang string) []Target {
+	if *NoCache {
+		return targets
+	}
+
+	var client *CachedClient
+	for i, target := range targets {
+		if target.Lookup.Kind != LookupCoordinates || target.Lookup.Place != "" {
+			continue
+		}
+		if client == nil {
+			var err error
+			if client, err = NewSearchClient(); err != nil {
+				return targets
+			}
+		}
+
+		places, err := client.ReverseGeocode(ctx, target.Lookup.Lat, target.Lookup.Lon, 1)
+		if err == nil && len(places) > 0 {
+			targets[i].Lookup.Place = PlaceName(places[0], lang)
+		}
+	}
+	return targets
+}
+
+// RunGeocode runs the geocode command: with a place name in args it shows the
+// coordinates of the places of the name, otherwise the places near the
+// coordinates of the --lat and --lon lookups, with the names in the language lang.
+func RunGeocode(ctx context.Context, args []string, targets []Target, lang string) error {
+	// The format is checked before the requests.
+	if _, err := DataPrinter("geocode"); err != nil {
+		return err
+	}
+
+	client, err := NewSearchClient()
+	if err != nil {
+		return err
+	}
+
+	var places []goopenweathermapapi.Place
+	if len(args) > 0 {
+		if places, err = client.Geocode(ctx, strings.Join(args, " "), geocodeLimit); err != nil {
+			return err
+		}
+		return ShowPlaces(places, lang)
+	}
+
+	if len(targets) == 0 {
+		return &UsageError{Message: "geocode needs a place name or --lat and --lon, e.g. goweather geocode London,gb"}
+	}
+	for _, target := range targets {
+		if target.Lookup.Kind != LookupCoordinates {
+			return &UsageError{Message: fmt.Sprintf("geocode needs a place name or --lat and --lon, not a %s", target.Lookup)}
+		}
+		found, err := client.ReverseGeocode(ctx, target.Lookup.Lat, target.Lookup.Lon, geocodeLimit)
+		if err != nil {
+			return err
+		}
+		places = append(places, found...)
+	}
+	return ShowPlaces(places, lang)
+}
+
+// ShowPlaces prints the places found by the geocode command with their local
+// names in the language lang.
+func ShowPlaces(places []goopenweathermapapi.Place, lang string) error {
+	printer, err := DataPrinter("geocode")
+	if err != nil {
+		return err
+	}
+
+	if printer != nil {
+		transformer := make([]map[string]interface{}, 0, len(places))
+		for _, place := range places {
+			transformer = append(transformer, map[string]interface{}{
+				"name":       place.Name,
+				"local_name": place.LocalName(lang),
+				"state":      place.State,
+				"country":    place.Country,
+				"lat":        place.Lat,
+				"lon":        place.Lon,
+			})
+		}
+		return printer(os.Stdout, transformer)
+	}
+
+	if len(places) == 0 {
+		fmt.Println("No places found")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Name\tState\tCountry\tLat\tLon")
+	for _, place := range places {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%.4f\t%.4f\n", place.LocalName(lang), place.State, place.Country, place.Lat, place.Lon)
+	}
+	return tw.Flush()
+}
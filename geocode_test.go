@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/belovai/goweather/goopenweathermapapi"
+)
+
+const reverseJson = `[{"name":"City of Westminster","local_names":{"de":"Westminster","en":"City of Westminster"},"lat":51.5,"lon":-0.14,"country":"GB","state":"England"}]`
+
+func TestReverseGeocode(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		query := r.URL.Query()
+		if r.URL.Path != "/geo/1.0/reverse" || query.Get("lat") != "51.5" || query.Get("lon") != "-0.14" || query.Get("appid") != "appid" {
+			t.Error("Error in reverse request: " + r.URL.String())
+		}
+		w.Write([]byte(reverseJson))
+	}))
+	defer server.Close()
+
+	client := &CachedClient{
+		Client: goopenweathermapapi.NewClient("appid", goopenweathermapapi.WithBaseURL(server.URL+"/data/2.5/")),
+		Cache:  &Cache{Dir: t.TempDir(), TTL: time.Hour},
+	}
+
+	places, err := client.ReverseGeocode(context.Background(), 51.5, -0.14, 1)
+	if err != nil || len(places) != 1 || places[0].Country != "GB" {
+		t.Fatal("Error in reverse geocode", err)
+	}
+
+	client.ReverseGeocode(context.Background(), 51.5, -0.14, 1)
+	if requests != 1 {
+		t.Error("Error in cached places")
+	}
+
+	if name := PlaceName(places[0], "de"); name != "Westminster, England, GB" {
+		t.Error("Error in place name: " + name)
+	}
+
+	if name := PlaceName(places[0], "hu"); name != "City of Westminster, England, GB" {
+		t.Error("Error in place name fallback: " + name)
+	}
+}
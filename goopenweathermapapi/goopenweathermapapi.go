@@ -0,0 +1,519 @@
+package goopenweathermapapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Client api client
+// GeoURL is the url of the geocoding api, if it is empty it is geo/1.0/ next to the
+// data/2.5/ of BaseURL.
+type Client struct {
+	APPID      string
+	BaseURL    string
+	GeoURL     string
+	HTTPClient *http.Client
+	UserAgent  string
+	Retry      RetryPolicy
+}
+
+// RetryPolicy of the requests. Network failures, 5xx responses and 429 responses are
+// retried MaxRetries times with exponential backoff and jitter starting from BaseDelay,
+// each delay is capped at MaxDelay. A Retry-After header is honored, if it asks for
+// a longer wait than MaxDelay the request is not retried.
+type RetryPolicy struct {
+	MaxRetries int
+	BaseDelay  time.Duration
+	MaxDelay   time.Duration
+}
+
+// Option configures the Client, see the With* functions
+type Option func(*Client)
+
+const apiURL = "https://api.openweathermap.org/data/2.5/"
+
+// WithBaseURL sets the url of the api, e.g. a caching proxy or a test server.
+// Default is https://api.openweathermap.org/data/2.5/
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		if !strings.HasSuffix(baseURL, "/") {
+			baseURL += "/"
+		}
+		c.BaseURL = baseURL
+	}
+}
+
+// WithGeoURL sets the url of the geocoding api.
+// Default is https://api.openweathermap.org/geo/1.0/ or geo/1.0/ next to the url set by WithBaseURL
+func WithGeoURL(geoURL string) Option {
+	return func(c *Client) {
+		if !strings.HasSuffix(geoURL, "/") {
+			geoURL += "/"
+		}
+		c.GeoURL = geoURL
+	}
+}
+
+// WithHTTPClient sets the http client used for the requests, nil is a new http.Client
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		if httpClient == nil {
+			httpClient = &http.Client{}
+		}
+		c.HTTPClient = httpClient
+	}
+}
+
+// WithTransport sets the RoundTripper of the http client
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		httpClient := *c.HTTPClient
+		httpClient.Transport = transport
+		c.HTTPClient = &httpClient
+	}
+}
+
+// WithTimeout sets the timeout of the requests, zero means no timeout
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		httpClient := *c.HTTPClient
+		httpClient.Timeout = timeout
+		c.HTTPClient = &httpClient
+	}
+}
+
+// WithRetry sets the retry policy, by default requests are not retried
+func WithRetry(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.Retry = policy
+	}
+}
+
+// WithUserAgent sets the User-Agent header of the requests
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.UserAgent = userAgent
+	}
+}
+
+// Kinds of errors returned by the client. Use errors.Is to check the kind of an *Error.
+// ErrRequest is a request which could not be made, e.g. with a malformed BaseURL, it is
+// not retried.
+var (
+	ErrRequest       = errors.New("invalid request")
+	ErrInvalidAPIKey = errors.New("invalid API key")
+	ErrCityNotFound  = errors.New("city not found")
+	ErrRateLimited   = errors.New("rate limited")
+	ErrNetwork       = errors.New("network failure")
+	ErrDecode        = errors.New("decode failure")
+	ErrAPI           = errors.New("api error")
+)
+
+// Error is returned by the client on failure.
+// Kind is one of the Err* variables, StatusCode, Cod and Message are set when the api
+// responded with an error, Err is the underlying error of network and decode failures.
+// Attempts is the number of requests made, RetryAfter is the wait asked by the api.
+// Body is the response body of the api error as it was returned.
+type Error struct {
+	Kind       error
+	StatusCode int
+	Cod        string
+	Message    string
+	Body       []byte
+	Err        error
+	Attempts   int
+	RetryAfter time.Duration
+}
+
+func (e *Error) Error() string {
+	message := fmt.Sprintf("%s: %s (HTTP %d)", e.Kind, e.Message, e.StatusCode)
+	if e.Err != nil {
+		message = fmt.Sprintf("%s: %s", e.Kind, e.Err)
+	}
+	if e.Attempts > 1 {
+		message += fmt.Sprintf(", gave up after %d attempts", e.Attempts)
+	}
+	return message
+}
+
+// Is reports whether target is the kind of the error
+func (e *Error) Is(target error) bool {
+	return e.Kind == target
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// newResponseError builds an *Error from an api response with an error status
+func newResponseError(resp *http.Response, body []byte) error {
+	e := &Error{Kind: ErrAPI, StatusCode: resp.StatusCode, Message: resp.Status, Body: body, RetryAfter: retryAfter(resp)}
+
+	var errorResponse struct {
+		Cod     json.Number `json:"cod"`
+		Message string      `json:"message"`
+	}
+	if err := json.Unmarshal(body, &errorResponse); err == nil {
+		e.Cod = errorResponse.Cod.String()
+		if errorResponse.Message != "" {
+			e.Message = errorResponse.Message
+		}
+	}
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized:
+		e.Kind = ErrInvalidAPIKey
+	case http.StatusNotFound:
+		e.Kind = ErrCityNotFound
+	case http.StatusTooManyRequests:
+		e.Kind = ErrRateLimited
+	}
+
+	return e
+}
+
+// retryAfter parses the Retry-After header, which is either seconds or an http date
+func retryAfter(resp *http.Response) time.Duration {
+	value := resp.Header.Get("Retry-After")
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if date, err := http.ParseTime(value); err == nil && date.After(time.Now()) {
+		return time.Until(date)
+	}
+	return 0
+}
+
+// NewClient appid should be the openweathermap APPID
+// Options are applied in order, e.g. WithTimeout after WithHTTPClient sets the timeout
+// on a copy of the given http client.
+func NewClient(appid string, options ...Option) *Client {
+	c := &Client{APPID: appid, BaseURL: apiURL, HTTPClient: &http.Client{}}
+	for _, option := range options {
+		option(c)
+	}
+	return c
+}
+
+// Location selects the place of a request, see ByCityName, ByCityID, ByCoordinates
+// and ByZipCode
+type Location url.Values
+
+// ByCityName city name or city name and country code separated by comma.
+// Use ISO 3166 country codes.
+func ByCityName(city string) Location {
+	return Location{"q": {city}}
+}
+
+// ByCityID List of city ID city.list.json.gz can be downloaded here http://bulk.openweathermap.org/sample/
+func ByCityID(cityID int) Location {
+	return Location{"id": {strconv.Itoa(cityID)}}
+}
+
+// GroupLimit is the maximum number of city IDs of a group request
+const GroupLimit = 20
+
+// ByCityIDs city IDs of a request of the group endpoint, at most GroupLimit of them
+func ByCityIDs(cityIDs []int) Location {
+	ids := make([]string, 0, len(cityIDs))
+	for _, cityID := range cityIDs {
+		ids = append(ids, strconv.Itoa(cityID))
+	}
+	return Location{"id": {strings.Join(ids, ",")}}
+}
+
+// ByCoordinates lat, lon coordinates of the location of your interest
+func ByCoordinates(lat, lon float64) Location {
+	return Location{
+		"lat": {strconv.FormatFloat(lat, 'f', 2, 64)},
+		"lon": {strconv.FormatFloat(lon, 'f', 2, 64)},
+	}
+}
+
+// ByZipCode zip code or zip code and country code seprated by comma.
+// Please note if country is not specified then the search works for USA as a default.
+func ByZipCode(zip string) Location {
+	return Location{"zip": {zip}}
+}
+
+// CurrentWeather returns the current weather of the location.
+// Units possible values are: metric, imperial or empty string.
+// Lang possible values are: ar, bg, ca, cz, de, el, en, fa, fi, fr, gl, hr, hu, it,
+// ja, kr, la, lt, mk, nl, pl, pt, ro, ru, se, sk, sl, es, tr, ua, vi, zh_cn, zh_tw
+func (c *Client) CurrentWeather(ctx context.Context, location Location, units, lang string) (*CurrentWeather, error) {
+	var weather CurrentWeather
+	if err := c.getJSON(ctx, "weather", location, units, lang, &weather); err != nil {
+		return nil, err
+	}
+	return &weather, nil
+}
+
+// Forecast returns the weather forecast for 5 days with data every 3 hours.
+// Units and lang are the same as in CurrentWeather.
+func (c *Client) Forecast(ctx context.Context, location Location, units, lang string) (*Forecast, error) {
+	var forecast Forecast
+	if err := c.getJSON(ctx, "forecast", location, units, lang, &forecast); err != nil {
+		return nil, err
+	}
+	return &forecast, nil
+}
+
+// Geocode returns the places of a name with the direct geocoding api. The query is a city
+// name, state code (only for the US) and country code separated by comma. Limit is the
+// maximum number of places, the api returns at most 5.
+func (c *Client) Geocode(ctx context.Context, query string, limit int) ([]Place, error) {
+	return c.getPlaces(ctx, "direct", url.Values{"q": {query}, "limit": {strconv.Itoa(limit)}})
+}
+
+// ReverseGeocode returns the places near the coordinates with the reverse geocoding api.
+// Limit is the same as in Geocode.
+func (c *Client) ReverseGeocode(ctx context.Context, lat, lon float64, limit int) ([]Place, error) {
+	return c.getPlaces(ctx, "reverse", reverseParams(lat, lon, limit))
+}
+
+// reverseParams are the parameters of a reverse geocoding request
+func reverseParams(lat, lon float64, limit int) url.Values {
+	return url.Values{
+		"lat":   {strconv.FormatFloat(lat, 'f', -1, 64)},
+		"lon":   {strconv.FormatFloat(lon, 'f', -1, 64)},
+		"limit": {strconv.Itoa(limit)},
+	}
+}
+
+// getPlaces requests an endpoint of the geocoding api and decodes the places
+func (c *Client) getPlaces(ctx context.Context, endpoint string, params url.Values) ([]Place, error) {
+	body, err := c.GeoGet(ctx, endpoint, params)
+	if err != nil {
+		return nil, err
+	}
+	var places []Place
+	if err := Decode(body, &places); err != nil {
+		return nil, err
+	}
+	return places, nil
+}
+
+// Search types of Find: FindLike matches names containing the query, FindAccurate
+// matches the name exactly
+const (
+	FindLike     = "like"
+	FindAccurate = "accurate"
+)
+
+// ByName a search of Find, city name or city name and country code separated by comma
+func ByName(query, searchType string) Location {
+	return Location{"q": {query}, "type": {searchType}}
+}
+
+// Find searches cities by name with the find endpoint and returns all of them with their
+// current weather. searchType is FindLike or FindAccurate.
+// Units and lang are the same as in CurrentWeather.
+func (c *Client) Find(ctx context.Context, query, searchType, units, lang string) (*FindResult, error) {
+	var result FindResult
+	if err := c.getJSON(ctx, "find", ByName(query, searchType), units, lang, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Get requests an endpoint, e.g. "weather" or "forecast", and returns the raw response body.
+// Failed requests are retried by the retry policy of the client. Errors are always of type *Error.
+func (c *Client) Get(ctx context.Context, endpoint string, location Location, units, lang string) ([]byte, error) {
+	params := url.Values{}
+
+	params.Add("APPID", c.APPID)
+	for key, values := range location {
+		for _, value := range values {
+			params.Add(key, value)
+		}
+	}
+	params.Add("lang", lang)
+	params.Add("units", units)
+
+	return c.request(ctx, fmt.Sprintf("%s%s?%s", c.BaseURL, endpoint, params.Encode()))
+}
+
+// GeoGet requests an endpoint of the geocoding api, e.g. "direct" or "reverse", and returns
+// the raw response body. Errors are always of type *Error.
+func (c *Client) GeoGet(ctx context.Context, endpoint string, params url.Values) ([]byte, error) {
+	query := url.Values{"appid": {c.APPID}}
+	for key, values := range params {
+		query[key] = values
+	}
+
+	return c.request(ctx, fmt.Sprintf("%s%s?%s", c.geoURL(), endpoint, query.Encode()))
+}
+
+// geoURL returns GeoURL or geo/1.0/ next to the data/2.5/ of BaseURL
+func (c *Client) geoURL() string {
+	if c.GeoURL != "" {
+		return c.GeoURL
+	}
+	base, err := url.Parse(c.BaseURL)
+	if err != nil {
+		return c.BaseURL
+	}
+	return base.ResolveReference(&url.URL{Path: "../../geo/1.0/"}).String()
+}
+
+// request gets the url with the retry policy of the client
+func (c *Client) request(ctx context.Context, url string) ([]byte, error) {
+	for attempt := 1; ; attempt++ {
+		body, err := c.do(ctx, url)
+		if err == nil {
+			return body, nil
+		}
+
+		e := err.(*Error)
+		e.Attempts = attempt
+		delay, retry := c.retryDelay(ctx, attempt, e)
+		if !retry {
+			return body, e
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return body, e
+		case <-timer.C:
+		}
+	}
+}
+
+// do makes a single request
+func (c *Client) do(ctx context.Context, url string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, &Error{Kind: ErrRequest, Err: err}
+	}
+	if c.UserAgent != "" {
+		req.Header.Set("User-Agent", c.UserAgent)
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return nil, &Error{Kind: ErrNetwork, Err: err}
+	}
+
+	defer resp.Body.Close()
+
+	buff := new(bytes.Buffer)
+	if _, err := buff.ReadFrom(resp.Body); err != nil {
+		return nil, &Error{Kind: ErrNetwork, Err: err}
+	}
+
+	if resp.StatusCode >= 300 {
+		return buff.Bytes(), newResponseError(resp, buff.Bytes())
+	}
+
+	return buff.Bytes(), nil
+}
+
+// retryDelay decides whether the failed attempt should be retried and how long to wait
+func (c *Client) retryDelay(ctx context.Context, attempt int, e *Error) (time.Duration, bool) {
+	if attempt > c.Retry.MaxRetries || ctx.Err() != nil {
+		return 0, false
+	}
+	if e.Kind != ErrNetwork && e.Kind != ErrRateLimited && e.StatusCode < http.StatusInternalServerError {
+		return 0, false
+	}
+
+	if e.RetryAfter > 0 {
+		if c.Retry.MaxDelay > 0 && e.RetryAfter > c.Retry.MaxDelay {
+			return 0, false
+		}
+		return e.RetryAfter, true
+	}
+
+	delay := c.Retry.BaseDelay << uint(attempt-1)
+	if c.Retry.MaxDelay > 0 && (delay > c.Retry.MaxDelay || delay <= 0) {
+		delay = c.Retry.MaxDelay
+	}
+	if delay <= 0 {
+		return 0, true
+	}
+
+	//jitter between the half and the full delay
+	return delay/2 + time.Duration(rand.Int63n(int64(delay/2)+1)), true
+}
+
+// getJSON requests an endpoint and decodes the response into v
+func (c *Client) getJSON(ctx context.Context, endpoint string, location Location, units, lang string, v interface{}) error {
+	body, err := c.Get(ctx, endpoint, location, units, lang)
+	if err != nil {
+		return err
+	}
+	return Decode(body, v)
+}
+
+// Decode decodes a response body returned by Get into v, e.g. a *CurrentWeather
+func Decode(body []byte, v interface{}) error {
+	if err := json.Unmarshal(body, v); err != nil {
+		return &Error{Kind: ErrDecode, Err: err}
+	}
+	return nil
+}
+
+// GetWeatherByCityName You can call by city name or city name and country code
+// separated by comma. Use ISO 3166 country codes.
+// API responds with a list of results that match a searching word.
+// Units possible values are: metric, imperial or empty string.
+// Lang possible values are: ar, bg, ca, cz, de, el, en, fa, fi, fr, gl, hr, hu, it,
+// ja, kr, la, lt, mk, nl, pl, pt, ro, ru, se, sk, sl, es, tr, ua, vi, zh_cn, zh_tw
+func (c *Client) GetWeatherByCityName(city, units, lang string) (jsonString string, err error) {
+	body, err := c.Get(context.Background(), "weather", ByCityName(city), units, lang)
+	return string(body), err
+}
+
+// GetWeatherByCityID You can call by city id.
+// List of city ID city.list.json.gz can be downloaded here http://bulk.openweathermap.org/sample/
+// Units possible values are: metric, imperial or empty string.
+// Lang possible values are: ar, bg, ca, cz, de, el, en, fa, fi, fr, gl, hr, hu, it,
+// ja, kr, la, lt, mk, nl, pl, pt, ro, ru, se, sk, sl, es, tr, ua, vi, zh_cn, zh_tw
+func (c *Client) GetWeatherByCityID(cityID int, units, lang string) (jsonString string, err error) {
+	body, err := c.Get(context.Background(), "weather", ByCityID(cityID), units, lang)
+	return string(body), err
+}
+
+// GetWeatherByCoordinates You can call By geographic coordinates.
+// lat, lon coordinates of the location of your interest.
+// Units possible values are: metric, imperial or empty string.
+// Lang possible values are: ar, bg, ca, cz, de, el, en, fa, fi, fr, gl, hr, hu, it,
+// ja, kr, la, lt, mk, nl, pl, pt, ro, ru, se, sk, sl, es, tr, ua, vi, zh_cn, zh_tw
+func (c *Client) GetWeatherByCoordinates(lat, lon float64, units, lang string) (jsonString string, err error) {
+	body, err := c.Get(context.Background(), "weather", ByCoordinates(lat, lon), units, lang)
+	return string(body), err
+}
+
+// GetWeatherByZipCode You can call by zip code or zip code and country code seprated
+// by comma. Use ISO 3166 country codes.
+// Please note if country is not specified then the search works for USA as a default.
+// Units possible values are: metric, imperial or empty string.
+// Lang possible values are: ar, bg, ca, cz, de, el, en, fa, fi, fr, gl, hr, hu, it,
+// ja, kr, la, lt, mk, nl, pl, pt, ro, ru, se, sk, sl, es, tr, ua, vi, zh_cn, zh_tw
+func (c *Client) GetWeatherByZipCode(zip, units, lang string) (jsonString string, err error) {
+	body, err := c.Get(context.Background(), "weather", ByZipCode(zip), units, lang)
+	return string(body), err
+}
+
+// GetForecastByCityName You can search weather forecast for 5 days with data every 3 hours by city name.
+// Units possible values are: metric, imperial or empty string.
+// Lang possible values are: ar, bg, ca, cz, de, el, en, fa, fi, fr, gl, hr, hu, it,
+// ja, kr, la, lt, mk, nl, pl, pt, ro, ru, se, sk, sl, es, tr, ua, vi, zh_cn, zh_tw
+func (c *Client) GetForecastByCityName(city, units, lang string) (jsonString string, err error) {
+	body, err := c.Get(context.Background(), "forecast", ByCityName(city), units, lang)
+	return string(body), err
+}
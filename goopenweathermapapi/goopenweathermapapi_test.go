@@ -0,0 +1,262 @@
+package goopenweathermapapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+)
+
+// newResponse is an api response with the status, see newResponseError
+func newResponse(status int) *http.Response {
+	return &http.Response{
+		StatusCode: status,
+		Status:     fmt.Sprintf("%d %s", status, http.StatusText(status)),
+		Header:     http.Header{},
+	}
+}
+
+func TestResponseErrors(t *testing.T) {
+	tests := []struct {
+		status  int
+		body    string
+		kind    error
+		message string
+	}{
+		{401, `{"cod":401,"message":"Invalid API key."}`, ErrInvalidAPIKey, "invalid API key: Invalid API key. (HTTP 401)"},
+		{404, `{"cod":"404","message":"city not found"}`, ErrCityNotFound, "city not found: city not found (HTTP 404)"},
+		{429, `{"cod":429}`, ErrRateLimited, "rate limited: 429 Too Many Requests (HTTP 429)"},
+		{500, `Internal Server Error`, ErrAPI, "api error: 500 Internal Server Error (HTTP 500)"},
+	}
+
+	for _, test := range tests {
+		err := newResponseError(newResponse(test.status), []byte(test.body))
+
+		var apiError *Error
+		if !errors.As(err, &apiError) || !errors.Is(err, test.kind) {
+			t.Error("Error in kind of HTTP", test.status)
+			continue
+		}
+		if apiError.StatusCode != test.status || err.Error() != test.message {
+			t.Error("Error in message: " + err.Error())
+		}
+	}
+}
+
+func TestErrorCod(t *testing.T) {
+	err := newResponseError(newResponse(404), []byte(`{"cod":"404","message":"city not found"}`))
+
+	var apiError *Error
+	if !errors.As(err, &apiError) || apiError.Cod != "404" || apiError.Message != "city not found" || string(apiError.Body) != `{"cod":"404","message":"city not found"}` {
+		t.Error("Error in cod, message and body")
+	}
+	if errors.Is(err, ErrAPI) {
+		t.Error("Error in kind, only one kind should match")
+	}
+}
+
+func TestNetworkError(t *testing.T) {
+	cause := errors.New("connection refused")
+	err := &Error{Kind: ErrNetwork, Err: cause}
+	if !errors.Is(err, ErrNetwork) || !errors.Is(err, cause) || err.Error() != "network failure: connection refused" {
+		t.Error("Error in network failure: " + err.Error())
+	}
+}
+
+func TestOptions(t *testing.T) {
+	httpClient := &http.Client{}
+	c := NewClient("appid", WithBaseURL("http://localhost:8080/data/2.5"), WithHTTPClient(httpClient), WithTimeout(5*time.Second), WithUserAgent("goweather"))
+	if c.BaseURL != "http://localhost:8080/data/2.5/" || c.UserAgent != "goweather" {
+		t.Error("Error in base url and user agent")
+	}
+	if c.HTTPClient.Timeout != 5*time.Second || httpClient.Timeout != 0 {
+		t.Error("Error in timeout, it should be set on a copy of the http client")
+	}
+
+	c = NewClient("appid", WithHTTPClient(nil), WithTimeout(time.Second), WithTransport(http.DefaultTransport))
+	if c.HTTPClient == nil || c.HTTPClient.Timeout != time.Second || c.HTTPClient.Transport != http.DefaultTransport {
+		t.Error("Error in nil http client")
+	}
+}
+
+func TestBaseURL(t *testing.T) {
+	var userAgent, query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		userAgent, query = r.UserAgent(), r.URL.Path+"?"+r.URL.RawQuery
+		w.Write([]byte(`{"name":"London"}`))
+	}))
+	defer server.Close()
+
+	c := NewClient("appid", WithBaseURL(server.URL), WithUserAgent("goweather"))
+	body, err := c.GetWeatherByCityName("London,gb", "metric", "en")
+	if err != nil || body != `{"name":"London"}` {
+		t.Error("Error in request")
+	}
+	if userAgent != "goweather" || query != "/weather?APPID=appid&lang=en&q=London%2Cgb&units=metric" {
+		t.Error("Error in request: " + userAgent + " " + query)
+	}
+}
+
+// newTestServer responds with the body of the path, or city not found for unknown paths
+// and the city Nowhere. The query of the last request is stored in query
+func newTestServer(bodies map[string]string, query *url.Values) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*query = r.URL.Query()
+		body, ok := bodies[r.URL.Path]
+		if !ok || query.Get("q") == "Nowhere" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"cod":"404","message":"city not found"}`))
+			return
+		}
+		w.Write([]byte(body))
+	}))
+}
+
+func TestCurrentWeather(t *testing.T) {
+	var query url.Values
+	server := newTestServer(map[string]string{
+		"/weather":  `{"id":2643743,"name":"London","main":{"temp":12.5},"weather":[{"description":"light rain"}],"wind":{"speed":4.1}}`,
+		"/forecast": `{"cnt":1,"list":[{"dt":1541246400,"main":{"temp":11},"pop":0.2}],"city":{"name":"London"}}`,
+	}, &query)
+	defer server.Close()
+	c := NewClient("appid", WithBaseURL(server.URL))
+
+	weather, err := c.CurrentWeather(context.Background(), ByCoordinates(51.5085, -0.1257), "metric", "")
+	if err != nil || weather.Name != "London" || weather.Main.Temp != 12.5 || weather.Wind == nil || weather.Wind.Speed != 4.1 || weather.Rain != nil {
+		t.Error("Error in current weather")
+	}
+	if query.Get("lat") != "51.51" || query.Get("lon") != "-0.13" || query.Get("units") != "metric" {
+		t.Error("Error in coordinates: " + query.Encode())
+	}
+
+	forecast, err := c.Forecast(context.Background(), ByCityID(2643743), "", "")
+	if err != nil || len(forecast.List) != 1 || forecast.List[0].Pop != 0.2 || forecast.City.Name != "London" {
+		t.Error("Error in forecast")
+	}
+	if query.Get("id") != "2643743" {
+		t.Error("Error in city id: " + query.Encode())
+	}
+
+	if _, err := c.CurrentWeather(context.Background(), ByZipCode("94040,us"), "", ""); err != nil || query.Get("zip") != "94040,us" {
+		t.Error("Error in zip code: " + query.Encode())
+	}
+	if _, err := c.CurrentWeather(context.Background(), ByCityName("Nowhere"), "", ""); !errors.Is(err, ErrCityNotFound) {
+		t.Error("Error in city not found")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.CurrentWeather(ctx, ByCityName("London"), "", ""); !errors.Is(err, ErrNetwork) || !errors.Is(err, context.Canceled) {
+		t.Error("Error in canceled request")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	var weather CurrentWeather
+	if err := Decode([]byte(`{"name":"London","unknown":{"a":1}}`), &weather); err != nil || weather.Name != "London" {
+		t.Error("Error in decode")
+	}
+	if err := Decode([]byte("{"), &weather); !errors.Is(err, ErrDecode) || errors.Unwrap(err) == nil {
+		t.Error("Error in decode failure")
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	c := NewClient("appid", WithRetry(RetryPolicy{MaxRetries: 2, BaseDelay: time.Second, MaxDelay: 10 * time.Second}))
+	ctx := context.Background()
+
+	if delay, retry := c.retryDelay(ctx, 2, &Error{Kind: ErrNetwork}); !retry || delay < time.Second || delay > 2*time.Second {
+		t.Error("Error in backoff of network failure", delay)
+	}
+	if delay, retry := c.retryDelay(ctx, 1, &Error{Kind: ErrRateLimited, RetryAfter: 3 * time.Second}); !retry || delay != 3*time.Second {
+		t.Error("Error in Retry-After", delay)
+	}
+	if _, retry := c.retryDelay(ctx, 1, &Error{Kind: ErrRateLimited, RetryAfter: time.Minute}); retry {
+		t.Error("Error in Retry-After longer than MaxDelay")
+	}
+	if _, retry := c.retryDelay(ctx, 3, &Error{Kind: ErrNetwork}); retry {
+		t.Error("Error in MaxRetries")
+	}
+	if _, retry := c.retryDelay(ctx, 1, &Error{Kind: ErrCityNotFound, StatusCode: 404}); retry {
+		t.Error("Error in city not found")
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	resp := newResponse(429)
+	resp.Header.Set("Retry-After", "120")
+	if retryAfter(resp) != 2*time.Minute {
+		t.Error("Error in Retry-After seconds")
+	}
+	resp.Header.Set("Retry-After", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	if delay := retryAfter(resp); delay < 59*time.Minute || delay > time.Hour {
+		t.Error("Error in Retry-After date", delay)
+	}
+}
+
+func TestInvalidRequest(t *testing.T) {
+	c := NewClient("appid", WithBaseURL("http://[::1"), WithRetry(RetryPolicy{MaxRetries: 3}))
+	_, err := c.CurrentWeather(context.Background(), ByCityName("London"), "", "")
+
+	var apiError *Error
+	if !errors.Is(err, ErrRequest) || !errors.As(err, &apiError) || apiError.Attempts != 1 {
+		t.Error("Error in invalid request, it should not be retried")
+	}
+}
+
+func TestByCityIDs(t *testing.T) {
+	if ids := url.Values(ByCityIDs([]int{2643743, 2988507})).Get("id"); ids != "2643743,2988507" {
+		t.Error("Error in city ids: " + ids)
+	}
+}
+
+func TestFind(t *testing.T) {
+	var query url.Values
+	server := newTestServer(map[string]string{
+		"/find": `{"message":"accurate","cod":"200","count":2,"list":[{"id":4951788,"name":"Springfield","sys":{"country":"US"}},{"id":4409896,"name":"Springfield"}]}`,
+	}, &query)
+	defer server.Close()
+
+	result, err := NewClient("appid", WithBaseURL(server.URL)).Find(context.Background(), "Springfield", FindAccurate, "metric", "")
+	if err != nil || result.Count != 2 || len(result.List) != 2 || result.List[0].Id != 4951788 || result.List[0].Sys.Country != "US" {
+		t.Error("Error in find")
+	}
+	if query.Get("q") != "Springfield" || query.Get("type") != "accurate" {
+		t.Error("Error in find request: " + query.Encode())
+	}
+}
+
+func TestGeocode(t *testing.T) {
+	var query url.Values
+	server := newTestServer(map[string]string{
+		"/geo/1.0/direct":  `[{"name":"London","local_names":{"hu":"London","de":"London"},"lat":51.5073,"lon":-0.1276,"country":"GB","state":"England"}]`,
+		"/geo/1.0/reverse": `[{"name":"City of Westminster","local_names":{"de":"Westminster"},"lat":51.5,"lon":-0.14,"country":"GB"}]`,
+	}, &query)
+	defer server.Close()
+	c := NewClient("appid", WithBaseURL(server.URL+"/data/2.5/"))
+
+	places, err := c.Geocode(context.Background(), "London,gb", 5)
+	if err != nil || len(places) != 1 || places[0].Lat != 51.5073 || places[0].State != "England" {
+		t.Error("Error in geocode")
+	}
+	if query.Get("q") != "London,gb" || query.Get("limit") != "5" || query.Get("appid") != "appid" {
+		t.Error("Error in geocode request: " + query.Encode())
+	}
+
+	places, err = c.ReverseGeocode(context.Background(), 51.5, -0.14, 1)
+	if err != nil || len(places) != 1 || places[0].LocalName("DE") != "Westminster" || places[0].LocalName("hu") != "City of Westminster" {
+		t.Error("Error in reverse geocode")
+	}
+	if query.Get("lat") != "51.5" || query.Get("lon") != "-0.14" || query.Get("limit") != "1" {
+		t.Error("Error in reverse geocode request: " + query.Encode())
+	}
+
+	c = NewClient("appid", WithBaseURL("http://localhost/data/2.5/"), WithGeoURL(server.URL+"/geo/1.0"))
+	if _, err := c.Geocode(context.Background(), "London,gb", 5); err != nil {
+		t.Error("Error in geocoding url")
+	}
+}
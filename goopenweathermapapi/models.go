@@ -0,0 +1,198 @@
+package goopenweathermapapi
+
+import (
+	"strings"
+	"time"
+)
+
+// CurrentWeather is the response of the current weather endpoint.
+// Optional blocks and fields are pointers, nil means the api did not report them.
+type CurrentWeather struct {
+	Coord      Coord          `json:"coord"`
+	Weather    []Weather      `json:"weather"`
+	Base       string         `json:"base"`
+	Main       Main           `json:"main"`
+	Visibility *int           `json:"visibility"`
+	Wind       *Wind          `json:"wind"`
+	Clouds     *Clouds        `json:"clouds"`
+	Rain       *Precipitation `json:"rain"`
+	Snow       *Precipitation `json:"snow"`
+	Dt         int64          `json:"dt"`
+	Sys        Sys            `json:"sys"`
+	Timezone   *int           `json:"timezone"`
+	Id         int            `json:"id"`
+	Name       string         `json:"name"`
+	Cod        int            `json:"cod"`
+}
+
+// Place is a result of the geocoding api. LocalNames are the names of the place by
+// language code.
+type Place struct {
+	Name       string            `json:"name"`
+	LocalNames map[string]string `json:"local_names"`
+	Lat        float64           `json:"lat"`
+	Lon        float64           `json:"lon"`
+	Country    string            `json:"country"`
+	State      string            `json:"state"`
+}
+
+// LocalName returns the name of the place in the language, or Name if it is not known
+func (p *Place) LocalName(lang string) string {
+	if name := p.LocalNames[strings.ToLower(lang)]; name != "" {
+		return name
+	}
+	return p.Name
+}
+
+// FindResult is the response of the find endpoint, the cities matching the search
+type FindResult struct {
+	Message string           `json:"message"`
+	Cod     string           `json:"cod"`
+	Count   int              `json:"count"`
+	List    []CurrentWeather `json:"list"`
+}
+
+// Forecast is the response of the 5 day / 3 hour forecast endpoint
+type Forecast struct {
+	Cod     string         `json:"cod"`
+	Message float64        `json:"message"`
+	Cnt     int            `json:"cnt"`
+	List    []ForecastItem `json:"list"`
+	City    ForecastCity   `json:"city"`
+}
+
+// ForecastItem is one 3 hour slot of the forecast
+type ForecastItem struct {
+	Dt         int64          `json:"dt"`
+	Main       Main           `json:"main"`
+	Weather    []Weather      `json:"weather"`
+	Clouds     *Clouds        `json:"clouds"`
+	Wind       Wind           `json:"wind"`
+	Visibility *int           `json:"visibility"`
+	Pop        float64        `json:"pop"`
+	Rain       *Precipitation `json:"rain"`
+	Snow       *Precipitation `json:"snow"`
+	Sys        ForecastSys    `json:"sys"`
+	DtTxt      string         `json:"dt_txt"`
+}
+
+type ForecastSys struct {
+	Pod string `json:"pod"`
+}
+
+type ForecastCity struct {
+	Id         int    `json:"id"`
+	Name       string `json:"name"`
+	Coord      Coord  `json:"coord"`
+	Country    string `json:"country"`
+	Population int    `json:"population"`
+	Timezone   int    `json:"timezone"`
+	Sunrise    int64  `json:"sunrise"`
+	Sunset     int64  `json:"sunset"`
+}
+
+type Coord struct {
+	Lon float64 `json:"lon"`
+	Lat float64 `json:"lat"`
+}
+
+type Weather struct {
+	Id          int    `json:"id"`
+	Main        string `json:"main"`
+	Description string `json:"description"`
+	Icon        string `json:"icon"`
+}
+
+type Main struct {
+	Temp      float64  `json:"temp"`
+	FeelsLike *float64 `json:"feels_like"`
+	TempMin   float64  `json:"temp_min"`
+	TempMax   float64  `json:"temp_max"`
+	Pressure  float64  `json:"pressure"`
+	Humidity  int      `json:"humidity"`
+	SeaLevel  *float64 `json:"sea_level"`
+	GrndLevel *float64 `json:"grnd_level"`
+	TempKf    *float64 `json:"temp_kf"`
+}
+
+type Wind struct {
+	Speed float64  `json:"speed"`
+	Deg   float64  `json:"deg"`
+	Gust  *float64 `json:"gust"`
+}
+
+type Clouds struct {
+	All int `json:"all"`
+}
+
+// Precipitation volumes are in mm for the last 1 or 3 hours
+type Precipitation struct {
+	OneHour    *float64 `json:"1h"`
+	ThreeHours *float64 `json:"3h"`
+}
+
+type Sys struct {
+	Type    int     `json:"type"`
+	Id      int     `json:"id"`
+	Message float64 `json:"message"`
+	Country string  `json:"country"`
+	Sunrise int64   `json:"sunrise"`
+	Sunset  int64   `json:"sunset"`
+	//Timezone is reported here instead of CurrentWeather.Timezone by the group endpoint
+	Timezone *int `json:"timezone"`
+}
+
+// Location returns the timezone of the city, or the local timezone if the api
+// did not report it.
+func (w *CurrentWeather) Location() *time.Location {
+	switch {
+	case w.Timezone != nil:
+		return time.FixedZone(w.Name, *w.Timezone)
+	case w.Sys.Timezone != nil:
+		return time.FixedZone(w.Name, *w.Sys.Timezone)
+	}
+	return time.Local
+}
+
+// Description returns the description of the primary weather condition
+func (w *CurrentWeather) Description() string {
+	return describe(w.Weather)
+}
+
+// Description returns the description of the primary weather condition
+func (i *ForecastItem) Description() string {
+	return describe(i.Weather)
+}
+
+func describe(weather []Weather) string {
+	if len(weather) == 0 {
+		return "unknown"
+	}
+	return weather[0].Description
+}
+
+// GustSpeed returns the speed of the gusts or zero if it is unknown
+func (w *Wind) GustSpeed() float64 {
+	if w == nil || w.Gust == nil {
+		return 0
+	}
+	return *w.Gust
+}
+
+// Volume returns the volume of the period ("1h" or "3h"), ok is false if it is unknown
+func (p *Precipitation) Volume(period string) (volume float64, ok bool) {
+	if p == nil {
+		return 0, false
+	}
+	switch period {
+	case "1h":
+		if p.OneHour != nil {
+			return *p.OneHour, true
+		}
+	case "3h":
+		if p.ThreeHours != nil {
+			return *p.ThreeHours, true
+		}
+	}
+	return 0, false
+}
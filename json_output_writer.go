@@ -2,44 +2,226 @@ package main
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
+	"io"
 	"strconv"
+	"time"
 )
 
+func init() {
+	RegisterFormat(OutputFormat{
+		Name:         "json",
+		Description:  "one JSON document, errors included",
+		New:          func() OutputWriterInterface { return &JsonOutputWriter{} },
+		StdoutErrors: true,
+	})
+}
+
+// JsonOutputWriter prints the records of JsonSchemaVersion, or the flat maps
+// of strings of version 1 with --json-schema=1.
 type JsonOutputWriter struct {
 }
 
-func (j *JsonOutputWriter) Render(w *WeatherResponse) {
-	tempSign := "°C"
-	speedSign := "m/s"
-	if *Units == "imperial" {
-		tempSign = "°F"
-		speedSign = "mph"
+func (j *JsonOutputWriter) Render(out io.Writer, w *WeatherResponse, opts RenderOptions) error {
+	if opts.JsonSchema == 1 {
+		return j.print(out, j.weather(w, opts.Lookup, opts.Response, opts))
+	}
+	return j.print(out, WeatherRecord(w, opts.Lookup, opts.Response, opts))
+}
+
+func (j *JsonOutputWriter) RenderForecast(out io.Writer, f *ForecastResponse, opts RenderOptions) error {
+	if opts.JsonSchema == 1 {
+		return j.print(out, j.forecast(f, opts.Lookup, opts.Response, opts))
+	}
+	return j.print(out, ForecastRecords(f, opts.Lookup, opts.Response, opts))
+}
+
+func (j *JsonOutputWriter) RenderDaily(out io.Writer, d *DailyForecast, opts RenderOptions) error {
+	if opts.JsonSchema == 1 {
+		return j.print(out, j.daily(d, opts.Lookup, opts.Response, opts))
+	}
+	return j.print(out, DailyRecords(d, opts.Lookup, opts.Response, opts))
+}
+
+// RenderLocations prints the locations as one JSON array in their order. The
+// current weather is an object per location, forecasts are the rows of all
+// locations. A failed location is an object with its error.
+func (j *JsonOutputWriter) RenderLocations(out io.Writer, results []LocationResult, opts RenderOptions) error {
+	if opts.JsonSchema != 1 {
+		return j.print(out, LocationRecords(results, opts))
+	}
+
+	transformer := make([]map[string]interface{}, 0, len(results))
+	for _, result := range results {
+		var rows []map[string]interface{}
+		switch {
+		case result.Err != nil:
+			rows = append(rows, map[string]interface{}{
+				"lookup": result.Target.Lookup.Kind,
+				"error":  errorFields(result.Err),
+			})
+		case result.Weather != nil:
+			rows = append(rows, j.weather(result.Weather, result.Target.Lookup, result.Response, opts))
+		case result.Forecast != nil:
+			rows = j.forecast(result.Forecast, result.Target.Lookup, result.Response, opts)
+		case result.Daily != nil:
+			rows = j.daily(result.Daily, result.Target.Lookup, result.Response, opts)
+		}
+
+		for _, row := range rows {
+			row["location"] = result.Name()
+			transformer = append(transformer, row)
+		}
 	}
 
+	return j.print(out, transformer)
+}
+
+func (j *JsonOutputWriter) weather(w *WeatherResponse, lookup Lookup, response *Response, opts RenderOptions) map[string]interface{} {
+	tempSign, speedSign := opts.UnitSigns()
+
 	temp := fmt.Sprintf("%.0f%s", w.Main.Temp, tempSign)
 
 	wind := ""
-	if w.Wind.Speed > 0 {
-		wind = fmt.Sprintf(", %.1f %s (%s)", w.Wind.Speed, speedSign, CalculateDirections(w.Wind.Deg))
+	if w.Wind != nil && w.Wind.Speed > 0 {
+		wind = fmt.Sprintf(", %.1f %s (%s)", w.Wind.Speed, speedSign, WindDirection(w.Wind.Deg))
 	}
 
-	transformer := map[string]string{
+	transformer := map[string]interface{}{
 		"city":        w.Name,
-		"description": w.Weather[0].Description,
+		"description": w.Description(),
 		"temp":        temp,
 		"wind":        wind,
-		"pressure":    fmt.Sprintf("%d hPa", w.Main.Pressure),
+		"pressure":    fmt.Sprintf("%.0f hPa", w.Main.Pressure),
 		"humidity":    fmt.Sprintf("%d%%", w.Main.Humidity),
-		"sunrise":     strconv.Itoa(w.Sys.Sunrise),
-		"sunset":      strconv.Itoa(w.Sys.Sunset),
+		"sunrise":     strconv.FormatInt(w.Sys.Sunrise, 10),
+		"sunset":      strconv.FormatInt(w.Sys.Sunset, 10),
+		"lookup":      lookup.Kind,
+	}
+	if w.Main.FeelsLike != nil {
+		transformer["feels_like"] = fmt.Sprintf("%.0f%s", *w.Main.FeelsLike, tempSign)
+	}
+	if w.Wind != nil && w.Wind.Gust != nil {
+		transformer["gust"] = fmt.Sprintf("%.1f %s", *w.Wind.Gust, speedSign)
+	}
+	if rain, ok := w.Rain.Volume("1h"); ok {
+		transformer["rain"] = fmt.Sprintf("%.2f mm", rain)
+	}
+	if snow, ok := w.Snow.Volume("1h"); ok {
+		transformer["snow"] = fmt.Sprintf("%.2f mm", snow)
+	}
+
+	return j.addLookup(j.addStale(transformer, response), lookup)
+}
+
+func (j *JsonOutputWriter) forecast(f *ForecastResponse, lookup Lookup, response *Response, opts RenderOptions) []map[string]interface{} {
+	tempSign, speedSign := opts.UnitSigns()
+
+	transformer := make([]map[string]interface{}, 0, len(f.List))
+	for _, item := range f.List {
+		transformer = append(transformer, j.addLookup(j.addStale(map[string]interface{}{
+			"city":        f.City.Name,
+			"time":        strconv.FormatInt(item.Dt, 10),
+			"description": item.Description(),
+			"temp":        fmt.Sprintf("%.0f%s", item.Main.Temp, tempSign),
+			"wind":        fmt.Sprintf("%.1f %s (%s)", item.Wind.Speed, speedSign, WindDirection(item.Wind.Deg)),
+			"pressure":    fmt.Sprintf("%.0f hPa", item.Main.Pressure),
+			"humidity":    fmt.Sprintf("%d%%", item.Main.Humidity),
+			"pop":         fmt.Sprintf("%.0f%%", item.Pop*100),
+			"lookup":      lookup.Kind,
+		}, response), lookup))
 	}
 
-	jsonString, err := json.Marshal(transformer)
+	return transformer
+}
+
+func (j *JsonOutputWriter) daily(d *DailyForecast, lookup Lookup, response *Response, opts RenderOptions) []map[string]interface{} {
+	tempSign, speedSign := opts.UnitSigns()
+
+	transformer := make([]map[string]interface{}, 0, len(d.Days))
+	for _, day := range d.Days {
+		transformer = append(transformer, j.addLookup(j.addStale(map[string]interface{}{
+			"city":        d.City.Name,
+			"date":        day.Date,
+			"main":        day.Main,
+			"description": day.Description,
+			"temp_min":    fmt.Sprintf("%.0f%s", day.TempMin, tempSign),
+			"temp_max":    fmt.Sprintf("%.0f%s", day.TempMax, tempSign),
+			"rain":        fmt.Sprintf("%.1f mm", day.Rain),
+			"snow":        fmt.Sprintf("%.1f mm", day.Snow),
+			"wind_max":    fmt.Sprintf("%.1f %s", day.WindMax, speedSign),
+			"gust_max":    fmt.Sprintf("%.1f %s", day.GustMax, speedSign),
+			"pop_max":     fmt.Sprintf("%.0f%%", day.PopMax*100),
+			"lookup":      lookup.Kind,
+		}, response), lookup))
+	}
+
+	return transformer
+}
+
+// addStale marks the output stale if the api was not reachable, observed_at
+// is the time the data was fetched from the api.
+func (j *JsonOutputWriter) addStale(transformer map[string]interface{}, response *Response) map[string]interface{} {
+	if response != nil {
+		transformer["stale"] = response.Stale
+		transformer["observed_at"] = response.FetchedAt.UTC().Format(time.RFC3339)
+	}
+	return transformer
+}
+
+// addLookup adds the coordinates of a coordinates lookup, the --at position
+// they were decoded from and their place name, see ResolvePlaces.
+func (j *JsonOutputWriter) addLookup(transformer map[string]interface{}, lookup Lookup) map[string]interface{} {
+	if lookup.Kind == LookupCoordinates {
+		transformer["lat"] = roundDegrees(lookup.Lat)
+		transformer["lon"] = roundDegrees(lookup.Lon)
+	}
+	if lookup.At != "" {
+		transformer["at"] = lookup.At
+	}
+	if lookup.Airport != "" {
+		transformer["airport"] = lookup.Airport
+	}
+	if lookup.Place != "" {
+		transformer["place"] = lookup.Place
+	}
+	return transformer
+}
+
+// RenderError prints the error as a JSON object, to stdout where the successful
+// output goes as well.
+func (j *JsonOutputWriter) RenderError(out io.Writer, err error, opts RenderOptions) error {
+	if opts.JsonSchema == 1 {
+		return j.print(out, map[string]interface{}{"error": errorFields(err)})
+	}
+	return j.print(out, map[string]interface{}{"version": JsonSchemaVersion, "error": errorFields(err)})
+}
+
+// errorFields are the fields of the error objects of the JSON output.
+func errorFields(err error) map[string]interface{} {
+	fields := map[string]interface{}{
+		"code":        ExitCode(err),
+		"category":    ErrorCategory(err),
+		"message":     err.Error(),
+		"http_status": HTTPStatus(err),
+		"retryable":   Retryable(err),
+	}
+
+	var ambiguousCity *AmbiguousCityError
+	if errors.As(err, &ambiguousCity) {
+		fields["candidates"] = cityFields(ambiguousCity.Candidates)
+	}
+
+	return fields
+}
+
+func (j *JsonOutputWriter) print(out io.Writer, v interface{}) error {
+	jsonString, err := json.Marshal(v)
 	if err != nil {
-		log.Fatal(err)
+		return err
 	}
 
-	fmt.Println(string(jsonString))
+	_, err = fmt.Fprintln(out, string(jsonString))
+	return err
 }
@@ -0,0 +1,272 @@
+package main
+
+import (
+	"time"
+
+	"github.com/belovai/goweather/goopenweathermapapi"
+)
+
+// JsonSchemaVersion is the version of the typed JSON output, it is in the
+// version field of every record. Version 1 is the old flat map of strings,
+// see --json-schema.
+const JsonSchemaVersion = 2
+
+// Record is the typed JSON output of the current weather, of a forecast slot
+// or of a day of the daily forecast. The numbers are in the units of the
+// Units field, the fields which do not apply are left out.
+type Record struct {
+	Version  int      `json:"version"`
+	Location string   `json:"location,omitempty"`
+	City     string   `json:"city,omitempty"`
+	Country  string   `json:"country,omitempty"`
+	Lookup   string   `json:"lookup"`
+	Lat      *float64 `json:"lat,omitempty"`
+	Lon      *float64 `json:"lon,omitempty"`
+	At       string   `json:"at,omitempty"`
+	Airport  string   `json:"airport,omitempty"`
+	Place    string   `json:"place,omitempty"`
+
+	// Date is the day of the daily forecast, Time the time of the
+	// observation, of the forecast slot or the start of the day.
+	Date        string      `json:"date,omitempty"`
+	Time        *RecordTime `json:"time,omitempty"`
+	Main        string      `json:"main,omitempty"`
+	Description string      `json:"description,omitempty"`
+	Icon        string      `json:"icon,omitempty"`
+
+	Temp       *float64 `json:"temp,omitempty"`
+	FeelsLike  *float64 `json:"feels_like,omitempty"`
+	TempMin    *float64 `json:"temp_min,omitempty"`
+	TempMax    *float64 `json:"temp_max,omitempty"`
+	Pressure   *float64 `json:"pressure,omitempty"`
+	Humidity   *int     `json:"humidity,omitempty"`
+	Clouds     *int     `json:"clouds,omitempty"`
+	Visibility *int     `json:"visibility,omitempty"`
+	Pop        *float64 `json:"pop,omitempty"`
+
+	Wind          *RecordWind          `json:"wind,omitempty"`
+	Precipitation *RecordPrecipitation `json:"precipitation,omitempty"`
+	Sun           *RecordSun           `json:"sun,omitempty"`
+	Units         RecordUnits          `json:"units"`
+
+	// Stale is set if the API was not reachable and the record is from the
+	// cache, ObservedAt is when it was fetched from the API.
+	Stale      bool        `json:"stale"`
+	ObservedAt *RecordTime `json:"observed_at,omitempty"`
+
+	// Error is the error of a failed location of several locations.
+	Error map[string]interface{} `json:"error,omitempty"`
+}
+
+// RecordTime is a time in ISO 8601 in UTC and in the local time of the city,
+// or of --timezone.
+type RecordTime struct {
+	UTC   string `json:"utc"`
+	Local string `json:"local"`
+}
+
+// RecordWind is the wind, the maximums of the day in the daily forecast.
+type RecordWind struct {
+	Speed     float64  `json:"speed"`
+	Deg       *float64 `json:"deg,omitempty"`
+	Direction string   `json:"direction,omitempty"`
+	Gust      *float64 `json:"gust,omitempty"`
+}
+
+// RecordPrecipitation is the rain and the snow in mm in the Period: 1h for the
+// current weather, 3h for a forecast slot and 1d for a day. Null is unknown.
+type RecordPrecipitation struct {
+	Rain   *float64 `json:"rain"`
+	Snow   *float64 `json:"snow"`
+	Period string   `json:"period"`
+}
+
+type RecordSun struct {
+	Sunrise RecordTime `json:"sunrise"`
+	Sunset  RecordTime `json:"sunset"`
+}
+
+// RecordUnits are the units of the numbers of the record.
+type RecordUnits struct {
+	System        string `json:"system"`
+	Temperature   string `json:"temperature"`
+	Speed         string `json:"speed"`
+	Pressure      string `json:"pressure"`
+	Precipitation string `json:"precipitation"`
+	Visibility    string `json:"visibility"`
+}
+
+func newRecordTime(t time.Time, zone *time.Location) *RecordTime {
+	return &RecordTime{UTC: t.UTC().Format(time.RFC3339), Local: t.In(zone).Format(time.RFC3339)}
+}
+
+// newRecord returns the fields of the lookup and the response of every record.
+func newRecord(lookup Lookup, response *Response, opts RenderOptions) Record {
+	tempSign, speedSign := opts.UnitSigns()
+	record := Record{
+		Version: JsonSchemaVersion,
+		Lookup:  lookup.Kind,
+		At:      lookup.At,
+		Airport: lookup.Airport,
+		Place:   lookup.Place,
+		Units: RecordUnits{
+			System:        opts.Units,
+			Temperature:   tempSign,
+			Speed:         speedSign,
+			Pressure:      "hPa",
+			Precipitation: "mm",
+			Visibility:    "m",
+		},
+	}
+	if response != nil {
+		record.Stale = response.Stale
+		record.ObservedAt = newRecordTime(response.FetchedAt, opts.In(time.Local))
+	}
+	return record
+}
+
+func (r *Record) setCoord(coord goopenweathermapapi.Coord) {
+	lat, lon := coord.Lat, coord.Lon
+	r.Lat, r.Lon = &lat, &lon
+}
+
+func (r *Record) setMain(main goopenweathermapapi.Main, weather []goopenweathermapapi.Weather) {
+	temp, tempMin, tempMax, pressure, humidity := main.Temp, main.TempMin, main.TempMax, main.Pressure, main.Humidity
+	r.Temp, r.FeelsLike, r.TempMin, r.TempMax = &temp, main.FeelsLike, &tempMin, &tempMax
+	r.Pressure, r.Humidity = &pressure, &humidity
+	if len(weather) > 0 {
+		r.Main = weather[0].Main
+		r.Icon = weather[0].Icon
+	}
+}
+
+func newRecordWind(wind goopenweathermapapi.Wind) *RecordWind {
+	deg := wind.Deg
+	return &RecordWind{Speed: wind.Speed, Deg: &deg, Direction: WindDirection(wind.Deg), Gust: wind.Gust}
+}
+
+func newRecordPrecipitation(rain, snow *goopenweathermapapi.Precipitation, period string) *RecordPrecipitation {
+	precipitation := &RecordPrecipitation{Period: period}
+	if volume, ok := rain.Volume(period); ok {
+		precipitation.Rain = &volume
+	}
+	if volume, ok := snow.Volume(period); ok {
+		precipitation.Snow = &volume
+	}
+	return precipitation
+}
+
+func newRecordSun(sunrise, sunset int64, zone *time.Location) *RecordSun {
+	return &RecordSun{
+		Sunrise: *newRecordTime(time.Unix(sunrise, 0), zone),
+		Sunset:  *newRecordTime(time.Unix(sunset, 0), zone),
+	}
+}
+
+// WeatherRecord returns the record of the current weather.
+func WeatherRecord(w *WeatherResponse, lookup Lookup, response *Response, opts RenderOptions) Record {
+	zone := opts.In(w.Location())
+	record := newRecord(lookup, response, opts)
+	record.City = w.Name
+	record.Country = w.Sys.Country
+	record.setCoord(w.Coord)
+	record.Time = newRecordTime(time.Unix(w.Dt, 0), zone)
+	record.Description = w.Description()
+	record.setMain(w.Main, w.Weather)
+	if w.Clouds != nil {
+		clouds := w.Clouds.All
+		record.Clouds = &clouds
+	}
+	record.Visibility = w.Visibility
+	if w.Wind != nil {
+		record.Wind = newRecordWind(*w.Wind)
+	}
+	record.Precipitation = newRecordPrecipitation(w.Rain, w.Snow, "1h")
+	record.Sun = newRecordSun(w.Sys.Sunrise, w.Sys.Sunset, zone)
+	return record
+}
+
+// ForecastRecords returns the records of the slots of the forecast.
+func ForecastRecords(f *ForecastResponse, lookup Lookup, response *Response, opts RenderOptions) []Record {
+	zone := opts.In(time.FixedZone(f.City.Name, f.City.Timezone))
+	records := make([]Record, 0, len(f.List))
+	for _, item := range f.List {
+		record := cityRecord(f.City, lookup, response, opts)
+		record.Time = newRecordTime(time.Unix(item.Dt, 0), zone)
+		record.Description = item.Description()
+		record.setMain(item.Main, item.Weather)
+		if item.Clouds != nil {
+			clouds := item.Clouds.All
+			record.Clouds = &clouds
+		}
+		record.Visibility = item.Visibility
+		pop := item.Pop
+		record.Pop = &pop
+		record.Wind = newRecordWind(item.Wind)
+		record.Precipitation = newRecordPrecipitation(item.Rain, item.Snow, "3h")
+		records = append(records, record)
+	}
+	return records
+}
+
+// DailyRecords returns the records of the days of the daily forecast.
+func DailyRecords(d *DailyForecast, lookup Lookup, response *Response, opts RenderOptions) []Record {
+	cityZone := time.FixedZone(d.City.Name, d.City.Timezone)
+	records := make([]Record, 0, len(d.Days))
+	for _, day := range d.Days {
+		day := day
+		record := cityRecord(d.City, lookup, response, opts)
+		record.Date = day.Date
+		if start, err := time.ParseInLocation("2006-01-02", day.Date, cityZone); err == nil {
+			record.Time = newRecordTime(start, opts.In(cityZone))
+		}
+		record.Main = day.Main
+		record.Description = day.Description
+		record.TempMin, record.TempMax = &day.TempMin, &day.TempMax
+		record.Pop = &day.PopMax
+		record.Wind = &RecordWind{Speed: day.WindMax, Gust: &day.GustMax}
+		record.Precipitation = &RecordPrecipitation{Rain: &day.Rain, Snow: &day.Snow, Period: "1d"}
+		records = append(records, record)
+	}
+	return records
+}
+
+// cityRecord returns the fields of the city of a forecast.
+func cityRecord(city goopenweathermapapi.ForecastCity, lookup Lookup, response *Response, opts RenderOptions) Record {
+	record := newRecord(lookup, response, opts)
+	record.City = city.Name
+	record.Country = city.Country
+	record.setCoord(city.Coord)
+	if city.Sunrise != 0 {
+		record.Sun = newRecordSun(city.Sunrise, city.Sunset, opts.In(time.FixedZone(city.Name, city.Timezone)))
+	}
+	return record
+}
+
+// LocationRecords returns the records of the locations in their order, with
+// their names in Location. A failed location is a record with its error. The
+// records are never nil, no locations are an empty list in the data formats.
+func LocationRecords(results []LocationResult, opts RenderOptions) []Record {
+	records := []Record{}
+	for _, result := range results {
+		var location []Record
+		switch {
+		case result.Err != nil:
+			record := newRecord(result.Target.Lookup, nil, opts)
+			record.Error = errorFields(result.Err)
+			location = []Record{record}
+		case result.Weather != nil:
+			location = []Record{WeatherRecord(result.Weather, result.Target.Lookup, result.Response, opts)}
+		case result.Forecast != nil:
+			location = ForecastRecords(result.Forecast, result.Target.Lookup, result.Response, opts)
+		case result.Daily != nil:
+			location = DailyRecords(result.Daily, result.Target.Lookup, result.Response, opts)
+		}
+
+		for _, record := range location {
+			record.Location = result.Name()
+			records = append(records, record)
+		}
+	}
+	return records
+}
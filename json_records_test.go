@@ -0,0 +1,61 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestWeatherRecord(t *testing.T) {
+	var weather WeatherResponse
+	if err := json.Unmarshal([]byte(weatherJson), &weather); err != nil {
+		t.Fatal(err)
+	}
+
+	zone, err := time.LoadLocation("Europe/Budapest")
+	if err != nil {
+		t.Skip(err)
+	}
+	fetched := time.Date(2018, 11, 3, 9, 0, 0, 0, time.UTC)
+	record := WeatherRecord(&weather, Lookup{Kind: LookupCity}, &Response{FetchedAt: fetched}, RenderOptions{Units: "metric", Timezone: zone})
+
+	if record.Version != 2 || *record.Temp != 12.3 || *record.FeelsLike != 11.1 || record.Units.Temperature != "°C" {
+		t.Error("Error in weather record")
+	}
+	if record.Wind.Speed != 4.1 || *record.Wind.Gust != 8.2 || *record.Precipitation.Rain != 0.25 || record.Precipitation.Snow != nil {
+		t.Error("Error in weather record wind and precipitation")
+	}
+	if record.Sun.Sunrise.UTC != "2018-11-03T06:53:20Z" || record.Sun.Sunrise.Local != "2018-11-03T07:53:20+01:00" {
+		t.Error("Error in weather record sunrise")
+	}
+	if record.ObservedAt.UTC != "2018-11-03T09:00:00Z" {
+		t.Error("Error in weather record observed at")
+	}
+}
+
+func TestForecastRecords(t *testing.T) {
+	var forecast ForecastResponse
+	if err := json.Unmarshal([]byte(forecastJson), &forecast); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := RenderOptions{Units: "imperial"}
+	records := ForecastRecords(&forecast, Lookup{Kind: LookupID}, nil, opts)
+	if len(records) != 1 || records[0].Time.Local != "2018-11-03T12:00:00Z" || *records[0].Pop != 0.35 || records[0].Precipitation.Period != "3h" {
+		t.Error("Error in forecast records")
+	}
+
+	days := DailyRecords(NewDailyForecast(&forecast), Lookup{Kind: LookupID}, nil, opts)
+	if len(days) != 1 || days[0].Date != "2018-11-03" || *days[0].TempMax != 11.4 || *days[0].Precipitation.Rain != 0.25 || days[0].Temp != nil {
+		t.Error("Error in daily records")
+	}
+
+	results := []LocationResult{
+		{Target: Target{Location: "home"}, Forecast: &forecast},
+		{Target: Target{Location: "cabin"}, Err: &UsageError{Message: "failed"}},
+	}
+	records = LocationRecords(results, opts)
+	if len(records) != 2 || records[0].Location != "home" || records[1].Location != "cabin" || records[1].Error["message"] != "failed" {
+		t.Error("Error in location records")
+	}
+}
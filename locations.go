@@ -0,0 +1,188 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/belovai/goweather/goopenweathermapapi"
+)
+
+// Target is a location to fetch. Location is the name of the named location of
+// the config file, if it came from one.
+type Target struct {
+	Lookup   Lookup
+	Location string
+}
+
+func (t Target) String() string {
+	if t.Location == "" {
+		return t.Lookup.String()
+	}
+	return fmt.Sprintf("%s (%s)", t.Location, t.Lookup)
+}
+
+// LocationResult is the outcome of one location of a multi-location run.
+// Either Err or the response of the command is set.
+type LocationResult struct {
+	Target   Target
+	Response *Response
+	Weather  *WeatherResponse
+	Forecast *ForecastResponse
+	Daily    *DailyForecast
+	Err      error
+}
+
+// Name is the named location, the place name of the coordinates, the city name
+// of the response or the lookup if the location failed.
+func (r *LocationResult) Name() string {
+	switch {
+	case r.Target.Location != "":
+		return r.Target.Location
+	case r.Target.Lookup.Place != "":
+		return r.Target.Lookup.Place
+	case r.Weather != nil:
+		return r.Weather.Name
+	case r.Forecast != nil:
+		return r.Forecast.City.Name
+	case r.Daily != nil:
+		return r.Daily.City.Name
+	}
+	return r.Target.Lookup.String()
+}
+
+// RunLocations runs the command for several locations and renders them
+// together. A failed location does not abort the others, the first failure
+// sets the exit code.
+func RunLocations(ctx context.Context, command string, targets []Target) error {
+	switch command {
+	case "", "current", "forecast", "daily":
+	default:
+		ShowHelp("Unknown command: " + command)
+	}
+
+	if *Workers < 1 {
+		return &UsageError{Message: fmt.Sprintf("invalid workers: %d", *Workers)}
+	}
+
+	client, err := NewCachedClient()
+	if err != nil {
+		return err
+	}
+
+	writer, err := NewOutputWriter(*Format)
+	if err != nil {
+		return err
+	}
+	opts, err := NewRenderOptions(command)
+	if err != nil {
+		return err
+	}
+
+	results := FetchLocations(ctx, client, command, targets, *Workers)
+	if err := writer.RenderLocations(os.Stdout, results, opts); err != nil {
+		return err
+	}
+
+	for _, result := range results {
+		if result.Err != nil {
+			return &ReportedError{Err: result.Err}
+		}
+	}
+	return nil
+}
+
+// FetchLocations fetches the targets of the command with at most workers
+// goroutines. The results are in the order of the targets. The current weather
+// of several city IDs is fetched with group requests.
+func FetchLocations(ctx context.Context, client *CachedClient, command string, targets []Target, workers int) []LocationResult {
+	results := make([]LocationResult, len(targets))
+
+	var pending, grouped []int
+	for i, target := range targets {
+		if (command == "" || command == "current") && target.Lookup.Kind == LookupID {
+			grouped = append(grouped, i)
+		} else {
+			pending = append(pending, i)
+		}
+	}
+	if len(grouped) > 1 {
+		fetchGroup(ctx, client, targets, grouped, results)
+	} else {
+		pending = append(pending, grouped...)
+	}
+
+	if workers > len(pending) {
+		workers = len(pending)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				results[j] = fetchLocation(ctx, client, command, targets[j])
+			}
+		}()
+	}
+
+	for _, i := range pending {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// fetchGroup fetches the current weather of the targets of the indexes, all of
+// them city ID lookups, into their results.
+func fetchGroup(ctx context.Context, client *CachedClient, targets []Target, indexes []int, results []LocationResult) {
+	ids := make([]int, 0, len(indexes))
+	for _, i := range indexes {
+		ids = append(ids, targets[i].Lookup.ID)
+	}
+
+	responses, errs := client.GetGroup(ctx, ids, *Units, *Lang)
+	for j, i := range indexes {
+		result := LocationResult{Target: targets[i], Response: responses[j], Err: errs[j]}
+		if result.Err != nil && result.Response == nil {
+			result.Response = ErrorResponse(CacheKey("group", targets[i].Lookup, *Units, *Lang), result.Err)
+		}
+		if result.Err == nil {
+			var weather WeatherResponse
+			if result.Err = goopenweathermapapi.Decode(result.Response.Body, &weather); result.Err == nil {
+				result.Weather = &weather
+			}
+		}
+		results[i] = result
+	}
+}
+
+func fetchLocation(ctx context.Context, client *CachedClient, command string, target Target) LocationResult {
+	result := LocationResult{Target: target}
+
+	if command == "" || command == "current" {
+		var weather WeatherResponse
+		result.Response, result.Err = fetchResponse(ctx, client, "weather", target.Lookup, &weather)
+		if result.Err == nil {
+			result.Weather = &weather
+		}
+		return result
+	}
+
+	var forecast ForecastResponse
+	result.Response, result.Err = fetchResponse(ctx, client, "forecast", target.Lookup, &forecast)
+	if result.Err != nil {
+		return result
+	}
+	if command == "daily" {
+		result.Daily = NewDailyForecast(&forecast)
+	} else {
+		result.Forecast = &forecast
+	}
+	return result
+}
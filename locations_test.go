@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/belovai/goweather/goopenweathermapapi"
+)
+
+func TestFetchLocations(t *testing.T) {
+	var running, maxRunning int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&running, 1)
+		defer atomic.AddInt32(&running, -1)
+		for {
+			max := atomic.LoadInt32(&maxRunning)
+			if n <= max || atomic.CompareAndSwapInt32(&maxRunning, max, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+
+		if r.URL.Query().Get("q") == "Nowhere" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"cod":"404","message":"city not found"}`))
+			return
+		}
+		w.Write([]byte(weatherJson))
+	}))
+	defer server.Close()
+
+	units, lang := "metric", ""
+	Units, Lang = &units, &lang
+
+	client := &CachedClient{
+		Client: goopenweathermapapi.NewClient("appid", goopenweathermapapi.WithBaseURL(server.URL)),
+	}
+	targets := []Target{
+		{Lookup: Lookup{Kind: LookupCity, City: "London,gb"}, Location: "office"},
+		{Lookup: Lookup{Kind: LookupCity, City: "Nowhere"}},
+		{Lookup: Lookup{Kind: LookupID, ID: 2643743}},
+		{Lookup: Lookup{Kind: LookupZip, Zip: "94040,us"}},
+	}
+
+	results := FetchLocations(context.Background(), client, "current", targets, 2)
+	if len(results) != len(targets) {
+		t.Fatal("Error in number of results")
+	}
+
+	for i, result := range results {
+		if result.Target != targets[i] {
+			t.Error("Error in order of results")
+		}
+	}
+
+	if results[0].Err != nil || results[0].Weather == nil || results[0].Name() != "office" {
+		t.Error("Error in named location")
+	}
+
+	if ExitCode(results[1].Err) != ExitCityNotFound || results[1].Name() != "city name Nowhere" {
+		t.Error("Error in failed location")
+	}
+	if results[1].Err.Error() != `city "Nowhere": not found (HTTP 404)` || HTTPStatus(results[1].Err) != 404 {
+		t.Error("Error in not found message: " + results[1].Err.Error())
+	}
+
+	if results[2].Err != nil || results[2].Name() != results[2].Weather.Name {
+		t.Error("Error in location after failure")
+	}
+
+	if maxRunning > 2 {
+		t.Error("Error in number of workers")
+	}
+}
+
+func TestFetchGroup(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/group" {
+			t.Error("Error in endpoint: " + r.URL.Path)
+		}
+		requests++
+
+		var list []string
+		for _, id := range strings.Split(r.URL.Query().Get("id"), ",") {
+			if id != "999" {
+				list = append(list, fmt.Sprintf(`{"id":%s,"name":"City %s","main":{"temp":10},"sys":{"timezone":3600}}`, id, id))
+			}
+		}
+		fmt.Fprintf(w, `{"cnt":%d,"list":[%s]}`, len(list), strings.Join(list, ","))
+	}))
+	defer server.Close()
+
+	units, lang := "metric", ""
+	Units, Lang = &units, &lang
+
+	client := &CachedClient{
+		Client: goopenweathermapapi.NewClient("appid", goopenweathermapapi.WithBaseURL(server.URL)),
+		Cache:  &Cache{Dir: t.TempDir(), TTL: time.Minute},
+	}
+	var targets []Target
+	for id := 1; id <= 25; id++ {
+		targets = append(targets, Target{Lookup: Lookup{Kind: LookupID, ID: id}})
+	}
+	targets = append(targets, Target{Lookup: Lookup{Kind: LookupID, ID: 999}})
+
+	results := FetchLocations(context.Background(), client, "current", targets, 4)
+	if requests != 2 {
+		t.Errorf("Error in number of group requests: %d", requests)
+	}
+
+	for i, result := range results[:25] {
+		if result.Err != nil || result.Weather == nil || result.Weather.Id != i+1 {
+			t.Fatal("Error in group result")
+		}
+	}
+
+	if results[0].Weather.Location().String() != "City 1" {
+		t.Error("Error in timezone of group result")
+	}
+
+	if ExitCode(results[25].Err) != ExitCityNotFound {
+		t.Error("Error in unknown city ID")
+	}
+
+	results = FetchLocations(context.Background(), client, "current", targets[:25], 4)
+	if requests != 2 || !results[24].Response.Cached || results[24].Response.Key != "group|city id 25|metric|" {
+		t.Error("Error in cached group results")
+	}
+	if entry := client.Cache.Get(CacheKey("weather", targets[0].Lookup, units, lang)); entry != nil {
+		t.Error("Error in group result cached as a weather response")
+	}
+
+	var queued []string
+	client.CacheOnly = true
+	client.BackgroundRefresh = func(key string) { queued = append(queued, key) }
+	results = FetchLocations(context.Background(), client, "current", []Target{targets[0], {Lookup: Lookup{Kind: LookupID, ID: 26}}}, 4)
+	if requests != 2 || results[0].Err != nil || !errors.Is(results[1].Err, ErrNoCachedData) || len(queued) != 1 || queued[0] != "group|city id 26|metric|" {
+		t.Error("Error in cache-only group results")
+	}
+}
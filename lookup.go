@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/belovai/goweather/goopenweathermapapi"
+)
+
+const (
+	LookupCity        = "city"
+	LookupID          = "id"
+	LookupCoordinates = "coordinates"
+	LookupZip         = "zip"
+	// LookupPosition and LookupAirport are decoded to a LookupCoordinates
+	// lookup by NewLookup.
+	LookupPosition = "position"
+	LookupAirport  = "airport"
+)
+
+// Lookup is the location of a request. At is the --at position and Airport is
+// the --airport code the coordinates were decoded from, Place is the name of
+// the coordinates found by reverse geocoding, see ResolvePlaces, or the name of
+// the airport.
+type Lookup struct {
+	Kind    string
+	City    string
+	ID      int
+	Lat     float64
+	Lon     float64
+	Zip     string
+	At      string
+	Airport string
+	Place   string
+}
+
+type LookupValues struct {
+	City    string
+	ID      string
+	Lat     string
+	Lon     string
+	At      string
+	Airport string
+	Zip     string
+}
+
+func (v LookupValues) kinds() []string {
+	var kinds []string
+	if v.City != "" {
+		kinds = append(kinds, LookupCity)
+	}
+	if v.ID != "" {
+		kinds = append(kinds, LookupID)
+	}
+	if v.Lat != "" || v.Lon != "" {
+		kinds = append(kinds, LookupCoordinates)
+	}
+	if v.At != "" {
+		kinds = append(kinds, LookupPosition)
+	}
+	if v.Airport != "" {
+		kinds = append(kinds, LookupAirport)
+	}
+	if v.Zip != "" {
+		kinds = append(kinds, LookupZip)
+	}
+	return kinds
+}
+
+// NewLookup builds a Lookup from raw option values. Exactly one lookup kind
+// must be present.
+func NewLookup(v LookupValues) (Lookup, error) {
+	kinds := v.kinds()
+	if len(kinds) == 0 {
+		return Lookup{}, errors.New("you must set the city, the city ID, the coordinates, the position, the airport or the zip code")
+	}
+	if len(kinds) > 1 {
+		return Lookup{}, fmt.Errorf("only one lookup can be used at a time, got: %s", strings.Join(kinds, ", "))
+	}
+
+	l := Lookup{Kind: kinds[0]}
+	switch l.Kind {
+	case LookupCity:
+		l.City = v.City
+	case LookupID:
+		id, err := strconv.Atoi(v.ID)
+		if err != nil || id <= 0 {
+			return Lookup{}, fmt.Errorf("invalid city ID: %s", v.ID)
+		}
+		l.ID = id
+	case LookupPosition:
+		lat, lon, err := ParsePosition(v.At)
+		if err != nil {
+			return Lookup{}, err
+		}
+		l = Lookup{Kind: LookupCoordinates, Lat: lat, Lon: lon, At: strings.TrimSpace(v.At)}
+	case LookupAirport:
+		airport, err := FindAirport(v.Airport)
+		if err != nil {
+			return Lookup{}, err
+		}
+		l = Lookup{
+			Kind:    LookupCoordinates,
+			Lat:     airport.Lat,
+			Lon:     airport.Lon,
+			Airport: strings.ToUpper(strings.TrimSpace(v.Airport)),
+			Place:   airport.String(),
+		}
+	case LookupCoordinates:
+		if v.Lat == "" || v.Lon == "" {
+			return Lookup{}, errors.New("latitude and longitude must be set together")
+		}
+		lat, err := strconv.ParseFloat(v.Lat, 64)
+		if err != nil || lat < -90 || lat > 90 {
+			return Lookup{}, fmt.Errorf("invalid latitude: %s", v.Lat)
+		}
+		lon, err := strconv.ParseFloat(v.Lon, 64)
+		if err != nil || lon < -180 || lon > 180 {
+			return Lookup{}, fmt.Errorf("invalid longitude: %s", v.Lon)
+		}
+		l.Lat = lat
+		l.Lon = lon
+	case LookupZip:
+		l.Zip = v.Zip
+	}
+
+	return l, nil
+}
+
+// Location converts the lookup to the location of the api client.
+func (l Lookup) Location() goopenweathermapapi.Location {
+	switch l.Kind {
+	case LookupID:
+		return goopenweathermapapi.ByCityID(l.ID)
+	case LookupCoordinates:
+		return goopenweathermapapi.ByCoordinates(l.Lat, l.Lon)
+	case LookupZip:
+		return goopenweathermapapi.ByZipCode(l.Zip)
+	default:
+		return goopenweathermapapi.ByCityName(l.City)
+	}
+}
+
+// Details is String with the exact coordinates and the position they were
+// decoded from, String is part of the cache keys.
+func (l Lookup) Details() string {
+	if l.Kind != LookupCoordinates {
+		return l.String()
+	}
+	details := fmt.Sprintf("coordinates %s,%s",
+		strconv.FormatFloat(roundDegrees(l.Lat), 'f', -1, 64),
+		strconv.FormatFloat(roundDegrees(l.Lon), 'f', -1, 64))
+	switch {
+	case l.At != "":
+		details += fmt.Sprintf(" (from %s)", l.At)
+	case l.Airport != "":
+		details += fmt.Sprintf(" (airport %s)", l.Airport)
+	}
+	return details
+}
+
+// roundDegrees rounds to 6 decimals, about 10 cm.
+func roundDegrees(degrees float64) float64 {
+	return math.Round(degrees*1e6) / 1e6
+}
+
+// Target is the lookup in messages, with the names quoted, e.g.
+// city "London,gb".
+func (l Lookup) Target() string {
+	switch l.Kind {
+	case LookupCity:
+		return fmt.Sprintf("city %q", l.City)
+	case LookupZip:
+		return fmt.Sprintf("zip code %q", l.Zip)
+	}
+	return l.Details()
+}
+
+func (l Lookup) String() string {
+	switch l.Kind {
+	case LookupID:
+		return fmt.Sprintf("city ID %d", l.ID)
+	case LookupCoordinates:
+		return fmt.Sprintf("coordinates %.2f,%.2f", l.Lat, l.Lon)
+	case LookupZip:
+		return fmt.Sprintf("zip code %s", l.Zip)
+	default:
+		return fmt.Sprintf("city name %s", l.City)
+	}
+}
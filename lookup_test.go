@@ -0,0 +1,45 @@
+package main
+
+import "testing"
+
+func TestNewLookup(t *testing.T) {
+	l, err := NewLookup(LookupValues{City: "London,gb"})
+	if err != nil || l.Kind != LookupCity || l.City != "London,gb" {
+		t.Error("Error in city lookup")
+	}
+
+	l, err = NewLookup(LookupValues{ID: "2643743"})
+	if err != nil || l.Kind != LookupID || l.ID != 2643743 {
+		t.Error("Error in id lookup")
+	}
+
+	l, err = NewLookup(LookupValues{Lat: "51.51", Lon: "-0.13"})
+	if err != nil || l.Kind != LookupCoordinates || l.Lat != 51.51 || l.Lon != -0.13 {
+		t.Error("Error in coordinates lookup")
+	}
+
+	l, err = NewLookup(LookupValues{Zip: "94040,us"})
+	if err != nil || l.Kind != LookupZip || l.Zip != "94040,us" {
+		t.Error("Error in zip lookup")
+	}
+
+	if _, err := NewLookup(LookupValues{}); err == nil {
+		t.Error("Error in empty lookup")
+	}
+
+	if _, err := NewLookup(LookupValues{City: "London,gb", Zip: "94040,us"}); err == nil {
+		t.Error("Error in mutually exclusive lookup")
+	}
+
+	if _, err := NewLookup(LookupValues{Lat: "51.51"}); err == nil {
+		t.Error("Error in lat without lon")
+	}
+
+	if _, err := NewLookup(LookupValues{Lat: "91", Lon: "0"}); err == nil {
+		t.Error("Error in lat out of range")
+	}
+
+	if _, err := NewLookup(LookupValues{ID: "abc"}); err == nil {
+		t.Error("Error in invalid id")
+	}
+}
@@ -1,33 +1,47 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"math"
 	"os"
+	"os/signal"
 	"strings"
+	"time"
 
-	"github.com/belovai/goopenweathermapapi"
+	"github.com/belovai/goweather/goopenweathermapapi"
 	"github.com/pborman/getopt/v2"
 )
 
-const apiURL = "https://api.openweathermap.org/data/2.5/weather"
-
-type ErrorResponse struct {
-	Cod     int    `json:"cod"`
-	Message string `json:"message"`
-}
-
-type OutputWriterInterface interface {
-	Render(w *WeatherResponse)
-}
-
 var Help *bool
-var City *string
 var Units *string
 var AppID *string
 var Format *string
+var JsonSchema *string
+var RawIndent *bool
+var Delimiter *string
 var Lang *string
+var APIURL *string
+var Timeout *time.Duration
+var Proxy *string
+var CACert *string
+var Retries *int
+var NoCache *bool
+var Refresh *bool
+var CacheTTL *time.Duration
+var MaxStale *time.Duration
+var CacheOnly *bool
+var ConfigFile *string
+var Workers *int
+var ResolveCity *bool
+var Pick *int
+var Timezone *string
+var Template *string
+var TemplateFile *string
+
+var Command string
+var Subcommand string
 
 func main() {
 	SetOptions()
@@ -36,78 +50,258 @@ func main() {
 		ShowHelp("")
 	}
 
-	if *City == "" {
-		ShowHelp("You must set the city")
+	config, err := LoadConfig(ConfigPath())
+	if err != nil {
+		Exit(&UsageError{Message: err.Error()})
+	}
+
+	targets, lookupSetting, lookupErr := ResolveTargets(config)
+
+	settings, err := ApplySettings(config, targets)
+	if err != nil {
+		Exit(err)
+	}
+
+	// The lookup error is reported after the settings, so the format of the
+	// environment and the config file is used for it.
+	if lookupErr != nil && Command != "config" && Command != "cities" && Command != "geocode" {
+		ShowHelp(lookupErr.Error())
 	}
 
-	var params map[string]string
-	params = map[string]string{
-		"q":     *City,
-		"units": *Units,
-		"APPID": *AppID,
+	if Command == "config" {
+		if Subcommand != "show" {
+			ShowHelp("Unknown config command, use: goweather config show")
+		}
+		if err := ShowConfig(config, append([]Setting{lookupSetting}, settings...)); err != nil {
+			Exit(err)
+		}
+		return
 	}
 
-	GetCurrentWerather(params)
+	if Command == "cities" {
+		if err := RunCities(Subcommand, getopt.Args()); err != nil {
+			Exit(err)
+		}
+		return
+	}
 
+	if *ResolveCity {
+		if targets, err = ResolveCities(targets); err != nil {
+			Exit(err)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if Command == "geocode" {
+		if err := RunGeocode(ctx, getopt.Args(), targets, *Lang); err != nil {
+			stop()
+			Exit(err)
+		}
+		refreshQueue.StartRefresh()
+		return
+	}
+
+	if targets, err = Disambiguate(ctx, targets); err != nil {
+		stop()
+		Exit(err)
+	}
+	targets = ResolvePlaces(ctx, targets, *Lang)
+
+	if len(targets) > 1 {
+		err = RunLocations(ctx, Command, targets)
+	} else {
+		err = Run(ctx, Command, targets[0].Lookup)
+	}
+	if err != nil {
+		stop()
+		Exit(err)
+	}
+	refreshQueue.StartRefresh()
 }
 
-func SetOptions() {
-	defaultUnits := os.Getenv("GOWEATHER_UNITS")
-	if defaultUnits == "" {
-		defaultUnits = "metric"
+func Run(ctx context.Context, command string, lookup Lookup) error {
+	switch command {
+	case "", "current", "forecast", "daily":
+	default:
+		ShowHelp("Unknown command: " + command)
+	}
+
+	writer, err := NewOutputWriter(*Format)
+	if err != nil {
+		return err
+	}
+	opts, err := NewRenderOptions(command)
+	if err != nil {
+		return err
+	}
+	opts.Lookup = lookup
+
+	if opts.Command == "current" {
+		currentWeather, response, err := GetCurrentWerather(ctx, lookup)
+		if err != nil {
+			return renderFailedBody(writer, response, opts, err)
+		}
+		opts.Response = response
+		return writer.Render(os.Stdout, currentWeather, opts)
+	}
+
+	forecast, response, err := GetForecast(ctx, lookup)
+	if err != nil {
+		return renderFailedBody(writer, response, opts, err)
+	}
+	opts.Response = response
+	if opts.Command == "daily" {
+		return NewDailyForecast(forecast).Render(os.Stdout, writer, opts)
+	}
+	return writer.RenderForecast(os.Stdout, forecast, opts)
+}
+
+// renderFailedBody renders the body of a failed request with a BodyWriter and
+// returns the error of the request.
+func renderFailedBody(writer OutputWriterInterface, response *Response, opts RenderOptions, err error) error {
+	bodyWriter, ok := writer.(BodyWriter)
+	if !ok || response == nil {
+		return err
+	}
+	opts.Response = response
+	if renderErr := bodyWriter.RenderBody(os.Stdout, opts); renderErr != nil {
+		return renderErr
 	}
+	return err
+}
 
+func SetOptions() {
 	Help = getopt.BoolLong("help", 'h', "Shows this help")
-	City = getopt.StringLong("city", 'c', os.Getenv("GOWEATHER_CITY"), "City name and country code separated by comma. Use ISO 3166 country codes. Example: London,gb Default value will be your GOWEATHER_CITY environment varible.")
-	Units = getopt.EnumLong("units", 'u', []string{"imperial", "metric"}, defaultUnits, "Temperature is available in Fahrenheit and Celsius units. Possible values: imperial, metric. Default value will be metric if your GOWEATHER_UNITS not set.")
-	AppID = getopt.StringLong("appid", 'a', os.Getenv("GOWEATHER_APPID"), "Your APPID from https://openweathermap.org. Default value will be your GOWEATHER_APPID environment variable.")
-	Format = getopt.EnumLong("format", 'f', []string{"pretty", "json"}, "pretty", "Output format. Possible values: pretty, json. Default value is pretty")
-	Lang = getopt.StringLong("lang", 'l', os.Getenv("GOWEATHER_LANG"), "API language")
-	getopt.Parse()
+	getopt.FlagLong(lookupFlag("city"), "city", 'c', "City name and country code separated by comma. Use ISO 3166 country codes. Example: London,gb Can be repeated. Default value will be your GOWEATHER_CITY environment varible.")
+	Units = getopt.EnumLong("units", 'u', []string{"imperial", "metric"}, "metric", "Temperature is available in Fahrenheit and Celsius units. Possible values: imperial, metric. Default value will be metric if your GOWEATHER_UNITS not set.")
+	AppID = getopt.StringLong("appid", 'a', "", "Your APPID from https://openweathermap.org. Default value will be your GOWEATHER_APPID environment variable.")
+	Format = getopt.EnumLong("format", 'f', FormatNames(), "pretty", "Output format. Possible values: "+strings.Join(FormatNames(), ", ")+". Default value is pretty")
+	JsonSchema = getopt.EnumLong("json-schema", 0, []string{"1", "2"}, "2", "Version of the JSON output. Version 1 is the old flat output of formatted strings. Possible values: 1, 2. Default value will be your GOWEATHER_JSON_SCHEMA environment variable or 2")
+	RawIndent = getopt.BoolLong("raw-indent", 0, "Indent the response bodies of --format=raw")
+	Delimiter = getopt.StringLong("delimiter", 0, ",", "Delimiter of --format=csv, e.g. ';'. Default value will be your GOWEATHER_DELIMITER environment variable or a comma")
+	Lang = getopt.StringLong("lang", 'l', "", "API language. Default value will be your GOWEATHER_LANG environment variable.")
+	getopt.FlagLong(lookupFlag("id"), "id", 'i', "City ID. List of city IDs can be downloaded from http://bulk.openweathermap.org/sample/ Can be repeated. Default value will be your GOWEATHER_ID environment variable.")
+	getopt.FlagLong(lookupFlag("lat"), "lat", 0, "Latitude of the location, use it together with --lon. Can be repeated. Default value will be your GOWEATHER_LAT environment variable.")
+	getopt.FlagLong(lookupFlag("lon"), "lon", 0, "Longitude of the location, use it together with --lat. Can be repeated. Default value will be your GOWEATHER_LON environment variable.")
+	getopt.FlagLong(lookupFlag("at"), "at", 0, "Position as degrees, minutes and seconds like \"51°30'N 0°7'W\", a geohash like gcpvj0 or a plus code like 9C3XGV00+, decoded to the coordinates. Can be repeated. Default value will be your GOWEATHER_AT environment variable.")
+	getopt.FlagLong(lookupFlag("airport"), "airport", 0, "IATA or ICAO airport code, e.g. LHR or EGLL. Can be repeated. Default value will be your GOWEATHER_AIRPORT environment variable.")
+	getopt.FlagLong(lookupFlag("zip"), "zip", 'z', "Zip code and country code separated by comma. Example: 94040,us Can be repeated. Default value will be your GOWEATHER_ZIP environment variable.")
+	APIURL = getopt.StringLong("api-url", 0, "", "Base url of the API, e.g. a caching proxy. Default value will be your GOWEATHER_API_URL environment variable or https://api.openweathermap.org/data/2.5/")
+	Timeout = getopt.DurationLong("timeout", 't', 10*time.Second, "Timeout of the API requests, e.g. 5s. Default value is 10s")
+	Proxy = getopt.StringLong("proxy", 0, "", "Proxy url, e.g. http://proxy:3128. Default value comes from the HTTPS_PROXY environment variable")
+	CACert = getopt.StringLong("ca-cert", 0, "", "PEM file with additional CA certificates to trust")
+	Retries = getopt.IntLong("retries", 0, 2, "Number of retries of failed API requests with exponential backoff. Default value is 2")
+	NoCache = getopt.BoolLong("no-cache", 0, "Do not read or write the response cache")
+	Refresh = getopt.BoolLong("refresh", 0, "Ignore the cached responses but update the cache")
+	CacheTTL = getopt.DurationLong("cache-ttl", 0, 10*time.Minute, "How long the cached responses are used, e.g. 5m. Default value will be your GOWEATHER_CACHE_TTL environment variable or 10m")
+	MaxStale = getopt.DurationLong("max-stale", 0, 24*time.Hour, "When the API is not reachable, cached responses younger than this are shown marked as stale. 0 disables the fallback. Default value is 24h")
+	CacheOnly = getopt.BoolLong("cache-only", 0, "Only read the cache and never wait for the API, e.g. in shell prompts. Expired responses are refreshed in the background")
+	ConfigFile = getopt.StringLong("config", 0, "", "Config file. Default value will be your GOWEATHER_CONFIG environment variable or goweather/config.ini in your XDG config directory")
+	getopt.FlagLong(lookupFlag("location"), "location", 0, "Named location or group of the config file, e.g. home. Can be repeated. Default value will be your GOWEATHER_LOCATION environment variable or the location of the config file")
+	Workers = getopt.IntLong("workers", 0, 4, "Number of locations fetched at the same time. Default value is 4")
+	ResolveCity = getopt.BoolLong("resolve-city", 0, "Resolve --city to a city ID with the local city index, see cities import")
+	Timezone = getopt.StringLong("timezone", 0, "", "Timezone of the times, e.g. Europe/Budapest, UTC or Local. Default value will be your GOWEATHER_TIMEZONE environment variable or the timezone of the location")
+	Template = getopt.StringLong("template", 0, "", "Go text/template of --format=template, e.g. '{{.Location}}: {{temp .Temp}}'. Default value will be your GOWEATHER_TEMPLATE environment variable")
+	TemplateFile = getopt.StringLong("template-file", 0, "", "File with the template of --format=template. Default value will be your GOWEATHER_TEMPLATE_FILE environment variable")
+	Pick = getopt.IntLong("pick", 0, 0, "Pick the Nth city when --city matches several cities, otherwise you are asked on a terminal")
+	getopt.SetParameters("[current|forecast|daily|config show|cities import FILE|cities search TEXT|geocode [TEXT]]")
+	ParseOptions(os.Args)
+
+	// Options are allowed after the command as well, e.g. goweather forecast -c London,gb
+	if getopt.NArgs() > 0 {
+		Command = getopt.Arg(0)
+		ParseOptions(getopt.Args())
+	}
+
+	// And after the subcommand, e.g. goweather cities search -f json London
+	if (Command == "config" || Command == "cities") && getopt.NArgs() > 0 {
+		Subcommand = getopt.Arg(0)
+		ParseOptions(getopt.Args())
+	}
 }
 
+// ParseOptions works like getopt.Parse but exits with ExitUsage on error.
+func ParseOptions(args []string) {
+	if err := getopt.CommandLine.Getopt(args, nil); err != nil {
+		ShowHelp(err.Error())
+	}
+}
+
+// ShowHelp prints the usage and exits. With a message it is a usage error.
 func ShowHelp(message string) {
-	if message != "" {
-		fmt.Println(message)
+	if message == "" {
+		getopt.Usage()
+		os.Exit(ExitOK)
+	}
+	if Format != nil && outputFormats[*Format].StdoutErrors {
+		Exit(&UsageError{Message: message})
 	}
+	fmt.Fprintln(os.Stderr, message)
 	getopt.Usage()
-	os.Exit(0)
+	os.Exit(ExitUsage)
 }
 
-func GetCurrentWerather(params map[string]string) {
-
-	client := goopenweathermapapi.NewClient(*AppID)
+// GetCurrentWerather returns the current weather. The response is returned
+// with the error too if there is a body, see fetchResponse.
+func GetCurrentWerather(ctx context.Context, lookup Lookup) (*WeatherResponse, *Response, error) {
+	var currentWeather WeatherResponse
+	response, err := fetch(ctx, "weather", lookup, &currentWeather)
+	if err != nil {
+		return nil, response, err
+	}
+	return &currentWeather, response, nil
+}
 
-	weatherJson, err := client.GetWeatherByCityName(*City, *Units, *Lang)
+// GetForecast returns the 5 day forecast, the response is returned with the
+// error like by GetCurrentWerather.
+func GetForecast(ctx context.Context, lookup Lookup) (*ForecastResponse, *Response, error) {
+	var forecast ForecastResponse
+	response, err := fetch(ctx, "forecast", lookup, &forecast)
+	if err != nil {
+		return nil, response, err
+	}
+	return &forecast, response, nil
+}
 
+// fetch gets the endpoint through the cache and decodes the response into v.
+func fetch(ctx context.Context, endpoint string, lookup Lookup, v interface{}) (*Response, error) {
+	client, err := NewCachedClient()
 	if err != nil {
-		log.Println("Error on request: ", err)
-		var errorResponse ErrorResponse
-		if err := json.NewDecoder(strings.NewReader(weatherJson)).Decode(&errorResponse); err != nil {
-			log.Fatal("Decode:", err)
-		}
-		log.Println("Code:", errorResponse.Cod)
-		log.Fatal("Message: ", errorResponse.Message)
-		return
+		return nil, err
 	}
 
-	var currentWeather WeatherResponse
+	return fetchResponse(ctx, client, endpoint, lookup, v)
+}
 
-	if err := json.NewDecoder(strings.NewReader(weatherJson)).Decode(&currentWeather); err != nil {
-		log.Println(err)
+// fetchResponse is fetch with a client, it is safe to call from several
+// goroutines. If the body could not be decoded or it is an error of the api,
+// the response is returned with the error, e.g. for the raw format.
+func fetchResponse(ctx context.Context, client *CachedClient, endpoint string, lookup Lookup, v interface{}) (*Response, error) {
+	response, err := client.Get(ctx, endpoint, lookup, *Units, *Lang)
+	if err != nil {
+		response = ErrorResponse(CacheKey(endpoint, lookup, *Units, *Lang), err)
 	}
-
-	switch *Format {
-	case "pretty":
-		currentWeather.Render(&PrettyOutputWriter{})
-	case "json":
-		currentWeather.Render(&JsonOutputWriter{})
+	if errors.Is(err, goopenweathermapapi.ErrCityNotFound) {
+		return response, &NotFoundError{Lookup: lookup, Err: err}
 	}
+	if err != nil {
+		return response, err
+	}
+
+	return response, goopenweathermapapi.Decode(response.Body, v)
+}
 
+// WindDirection returns the compass direction of the wind degrees.
+func WindDirection(deg float64) string {
+	return CalculateDirections(int(math.Round(deg)))
 }
 
 func CalculateDirections(deg int) string {
 	directions := []string{"N", "NE", "NE", "E", "E", "SE", "SE", "S", "S", "SW", "SW", "W", "W", "NW", "NW", "N"}
 
+	deg = (deg%360 + 360) % 360
+
 	return directions[int(float64(deg)/22.5)]
 }
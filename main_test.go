@@ -43,4 +43,8 @@ func TestCalculateDirections(t *testing.T) {
 		t.Error("Error in NE 23")
 	}
 
+	if CalculateDirections(360) != "N" {
+		t.Error("Error in N 360")
+	}
+
 }
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	geohashAlphabet  = "0123456789bcdefghjkmnpqrstuvwxyz"
+	plusCodeAlphabet = "23456789CFGHJMPQRVWX"
+)
+
+// ParsePosition decodes the coordinates of --at: degrees, minutes and seconds
+// like 51°30'N 0°7'W, decimal degrees like 51.5,-0.12, a geohash like 9q8yyk
+// or a full plus code (Open Location Code) like 849VCWC8+R9. The coordinates
+// are the center of the area of a geohash or a plus code. A geohash is only
+// tried if the position is not valid DMS.
+func ParsePosition(s string) (float64, float64, error) {
+	s = strings.TrimSpace(s)
+
+	var lat, lon float64
+	var err error
+	switch {
+	case strings.Contains(s, "+"):
+		lat, lon, err = decodePlusCode(s)
+	case isDecimalPair(s):
+		lat, lon, err = parseDecimalPair(s)
+	default:
+		// Short DMS positions like 51n0w are geohashes as well, DMS wins.
+		lat, lon, err = parseDMS(s)
+		if err != nil && isGeohash(s) {
+			lat, lon = decodeGeohash(s)
+			err = nil
+		}
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid position %s: %s (positions look like 51°30'N 0°7'W, 51.5,-0.12, gcpvj0 or 9C3XGV00+)", s, err)
+	}
+	return lat, lon, nil
+}
+
+func isDecimalPair(s string) bool {
+	return strings.Count(s, ",") == 1 && strings.IndexFunc(s, unicode.IsLetter) < 0 && !strings.ContainsAny(s, "°º'′\"″")
+}
+
+func parseDecimalPair(s string) (float64, float64, error) {
+	parts := strings.Split(s, ",")
+	lat, errLat := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	lon, errLon := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if errLat != nil || errLon != nil {
+		return 0, 0, fmt.Errorf("expected latitude,longitude")
+	}
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return 0, 0, fmt.Errorf("out of range")
+	}
+	return lat, lon, nil
+}
+
+func isGeohash(s string) bool {
+	if s == "" || len(s) > 12 {
+		return false
+	}
+	for _, r := range strings.ToLower(s) {
+		if !strings.ContainsRune(geohashAlphabet, r) {
+			return false
+		}
+	}
+	return true
+}
+
+// decodeGeohash returns the center of the geohash, the bits of the longitude
+// and the latitude alternate starting with the longitude.
+func decodeGeohash(s string) (float64, float64) {
+	latMin, latMax := -90.0, 90.0
+	lonMin, lonMax := -180.0, 180.0
+	even := true
+	for _, r := range strings.ToLower(s) {
+		bits := strings.IndexRune(geohashAlphabet, r)
+		for mask := 16; mask > 0; mask >>= 1 {
+			if even {
+				if mid := (lonMin + lonMax) / 2; bits&mask != 0 {
+					lonMin = mid
+				} else {
+					lonMax = mid
+				}
+			} else {
+				if mid := (latMin + latMax) / 2; bits&mask != 0 {
+					latMin = mid
+				} else {
+					latMax = mid
+				}
+			}
+			even = !even
+		}
+	}
+	return (latMin + latMax) / 2, (lonMin + lonMax) / 2
+}
+
+// decodePlusCode returns the center of a full plus code. The first 10 digits
+// are pairs of latitude and longitude digits in base 20, the digits after them
+// refine a 5 by 4 grid. Short codes need a reference location and are not
+// supported.
+func decodePlusCode(s string) (float64, float64, error) {
+	code := strings.ToUpper(s)
+	sep := strings.Index(code, "+")
+	if sep != 8 || strings.Count(code, "+") != 1 {
+		if sep >= 0 && sep < 8 {
+			return 0, 0, fmt.Errorf("short plus codes are not supported, use the full code")
+		}
+		return 0, 0, fmt.Errorf("the + must be the 9th character of a plus code")
+	}
+
+	digits := code[:sep]
+	if pad := strings.Index(digits, "0"); pad >= 0 {
+		if pad == 0 || pad%2 != 0 || strings.Trim(digits[pad:], "0") != "" || len(code) > sep+1 {
+			return 0, 0, fmt.Errorf("invalid padding of the plus code")
+		}
+		digits = digits[:pad]
+	}
+	if len(code) == sep+2 {
+		return 0, 0, fmt.Errorf("a plus code needs at least 2 characters after the +")
+	}
+	digits += code[sep+1:]
+
+	values := make([]int, 0, len(digits))
+	for _, r := range digits {
+		value := strings.IndexRune(plusCodeAlphabet, r)
+		if value < 0 {
+			return 0, 0, fmt.Errorf("invalid plus code character %q", r)
+		}
+		values = append(values, value)
+	}
+	if values[0] > 8 || (len(values) > 1 && values[1] > 17) {
+		return 0, 0, fmt.Errorf("out of range")
+	}
+
+	lat, lon := -90.0, -180.0
+	latSize, lonSize := 400.0, 400.0
+	for i, value := range values {
+		if i < 10 {
+			if i%2 == 0 {
+				latSize /= 20
+				lat += float64(value) * latSize
+			} else {
+				lonSize /= 20
+				lon += float64(value) * lonSize
+			}
+			continue
+		}
+		latSize /= 5
+		lonSize /= 4
+		lat += float64(value/4) * latSize
+		lon += float64(value%4) * lonSize
+	}
+	return lat + latSize/2, lon + lonSize/2, nil
+}
+
+// parseDMS parses two coordinates of degrees, optional minutes and seconds and
+// a hemisphere letter, before or after the numbers: 51°30'26"N 0°7'39"W,
+// N51 30 W0 7 or 51.5N 0.12W.
+func parseDMS(s string) (float64, float64, error) {
+	type coordinate struct {
+		numbers    []float64
+		hemisphere rune
+	}
+
+	var tokens []string
+	var current strings.Builder
+	flush := func() {
+		if current.Len() > 0 {
+			tokens = append(tokens, current.String())
+			current.Reset()
+		}
+	}
+	for _, r := range strings.ToUpper(s) {
+		switch {
+		case unicode.IsDigit(r) || r == '.':
+			current.WriteRune(r)
+		case strings.ContainsRune("NSEW", r):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsSpace(r) || strings.ContainsRune("°º'′\"″,", r):
+			flush()
+		default:
+			return 0, 0, fmt.Errorf("unexpected %q", r)
+		}
+	}
+	flush()
+	if len(tokens) == 0 {
+		return 0, 0, fmt.Errorf("empty position")
+	}
+
+	// The hemisphere letter ends the coordinate, or starts it if the first
+	// token is a letter.
+	prefix := strings.ContainsAny(tokens[0], "NSEW")
+	var coordinates []coordinate
+	var c coordinate
+	for _, token := range tokens {
+		if !strings.ContainsAny(token, "NSEW") {
+			number, err := strconv.ParseFloat(token, 64)
+			if err != nil {
+				return 0, 0, fmt.Errorf("invalid number %s", token)
+			}
+			c.numbers = append(c.numbers, number)
+			continue
+		}
+		if prefix {
+			if c.hemisphere != 0 {
+				coordinates = append(coordinates, c)
+			}
+			c = coordinate{hemisphere: rune(token[0])}
+		} else {
+			c.hemisphere = rune(token[0])
+			coordinates = append(coordinates, c)
+			c = coordinate{}
+		}
+	}
+	if prefix {
+		coordinates = append(coordinates, c)
+	} else if len(c.numbers) > 0 {
+		return 0, 0, fmt.Errorf("missing hemisphere, use N, S, E or W")
+	}
+	if len(coordinates) != 2 {
+		return 0, 0, fmt.Errorf("expected a latitude and a longitude")
+	}
+
+	var lat, lon float64
+	var hasLat, hasLon bool
+	for _, c := range coordinates {
+		if len(c.numbers) == 0 || len(c.numbers) > 3 {
+			return 0, 0, fmt.Errorf("expected degrees, minutes and seconds")
+		}
+		degrees := c.numbers[0]
+		scale := 1.0
+		for _, number := range c.numbers[1:] {
+			if number >= 60 {
+				return 0, 0, fmt.Errorf("minutes and seconds must be less than 60")
+			}
+			scale *= 60
+			degrees += number / scale
+		}
+		if c.hemisphere == 'S' || c.hemisphere == 'W' {
+			degrees = -degrees
+		}
+
+		switch c.hemisphere {
+		case 'N', 'S':
+			lat, hasLat = degrees, true
+		case 'E', 'W':
+			lon, hasLon = degrees, true
+		}
+	}
+	if !hasLat || !hasLon {
+		return 0, 0, fmt.Errorf("expected a latitude (N or S) and a longitude (E or W)")
+	}
+	if lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return 0, 0, fmt.Errorf("out of range")
+	}
+	return lat, lon, nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParsePosition(t *testing.T) {
+	positions := map[string][2]float64{
+		`51°30'N 0°7'W`:          {51.5, -0.11667},
+		`51°30'26"N, 0°7'39"W`:   {51.50722, -0.1275},
+		"N51 30 W0 7":            {51.5, -0.11667},
+		"33.9S 18.4E":            {-33.9, 18.4},
+		"51.5, -0.12":            {51.5, -0.12},
+		"9q8yyk":                 {37.77374, -122.41516},
+		"u4pruydqqvj":            {57.64911, 10.40744},
+		"849VCWC8+R9":            {37.42206, -122.08406},
+		"9C3XGV00+":              {51.525, -0.125},
+		"8FVC9G8F+6XQQ":          {47.36561, 8.52501},
+		"w21z7gy6":               {1.29544, 103.88449},
+		`40°26′46″N 79°58′56″W`:  {40.44611, -79.98222},
+		"  9C3XGV00+  ":          {51.525, -0.125},
+		"40 26 46 n 79 58 56 w":  {40.44611, -79.98222},
+		"w 79 58 56 n 40 26 46 ": {40.44611, -79.98222},
+		"51n0w":                  {51, 0},
+		"gcpvjs":                 {51.52863, -0.10437},
+	}
+
+	for position, expected := range positions {
+		lat, lon, err := ParsePosition(position)
+		if err != nil || math.Abs(lat-expected[0]) > 1e-4 || math.Abs(lon-expected[1]) > 1e-4 {
+			t.Errorf("Error in %q: %f,%f %v", position, lat, lon, err)
+		}
+	}
+
+	invalid := []string{
+		"",
+		"CWC8+R9",
+		"849VCW+R9",
+		"849VCWC8+R",
+		"9C3X0000+R9",
+		"9C3XGV0+",
+		"X49VCWC8+R9",
+		"51°30'N",
+		"51°30'N 10°W 3E",
+		"51°75'N 0°7'W",
+		"51°30'N 0°7'N",
+		"91N 0E",
+		"51.5, 190",
+		"London",
+	}
+	for _, position := range invalid {
+		if _, _, err := ParsePosition(position); err == nil {
+			t.Errorf("Error in invalid %q", position)
+		}
+	}
+}
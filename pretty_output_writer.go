@@ -1,34 +1,214 @@
 package main
 
 import (
+	"bufio"
 	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
 	"time"
+
+	"github.com/belovai/goweather/goopenweathermapapi"
 )
 
+func init() {
+	RegisterFormat(OutputFormat{
+		Name:        "pretty",
+		Description: "human readable text and tables",
+		New:         func() OutputWriterInterface { return &PrettyOutputWriter{} },
+	})
+}
+
+// PrettyOutputWriter writes human readable text. The output is buffered, the
+// first write error is returned when it is flushed.
 type PrettyOutputWriter struct {
 }
 
-func (p *PrettyOutputWriter) Render(w *WeatherResponse) {
-	tempSign := "°C"
-	speedSign := "m/s"
-	if *Units == "imperial" {
-		tempSign = "°F"
-		speedSign = "mph"
-	}
+func (p *PrettyOutputWriter) Render(out io.Writer, w *WeatherResponse, opts RenderOptions) error {
+	b := bufio.NewWriter(out)
+	tempSign, speedSign := opts.UnitSigns()
 
 	temp := fmt.Sprintf("%.0f%s", w.Main.Temp, tempSign)
+	if w.Main.FeelsLike != nil {
+		temp += fmt.Sprintf(" (feels like %.0f%s)", *w.Main.FeelsLike, tempSign)
+	}
 
 	wind := ""
-	if w.Wind.Speed > 0 {
-		wind = fmt.Sprintf(", %.1f %s (%s) wind", w.Wind.Speed, speedSign, CalculateDirections(w.Wind.Deg))
-	}
-
-	sunset := time.Unix(int64(w.Sys.Sunset), 0)
-	sunrise := time.Unix(int64(w.Sys.Sunrise), 0)
-	fmt.Printf("Current weather in %s:\n", w.Name)
-	fmt.Printf("%s, %s%s\n", w.Weather[0].Description, temp, wind)
-	fmt.Printf("Pressure: %d hPa\n", w.Main.Pressure)
-	fmt.Printf("Humidity: %d%%\n", w.Main.Humidity)
-	fmt.Printf("Sunset: %02d:%02d\n", sunset.Hour(), sunset.Minute())
-	fmt.Printf("Sunrise: %02d:%02d\n", sunrise.Hour(), sunrise.Minute())
+	if w.Wind != nil && w.Wind.Speed > 0 {
+		wind = fmt.Sprintf(", %.1f %s (%s) wind", w.Wind.Speed, speedSign, WindDirection(w.Wind.Deg))
+		if w.Wind.Gust != nil {
+			wind += fmt.Sprintf(", gusts %.1f %s", *w.Wind.Gust, speedSign)
+		}
+	}
+
+	zone := opts.In(w.Location())
+	sunset := time.Unix(w.Sys.Sunset, 0).In(zone)
+	sunrise := time.Unix(w.Sys.Sunrise, 0).In(zone)
+	fmt.Fprintf(b, "Current weather in %s:\n", p.placeName(w.Name, opts.Lookup))
+	p.printStale(b, opts.Response)
+	fmt.Fprintf(b, "%s, %s%s\n", w.Description(), temp, wind)
+	fmt.Fprintf(b, "Pressure: %.0f hPa\n", w.Main.Pressure)
+	fmt.Fprintf(b, "Humidity: %d%%\n", w.Main.Humidity)
+	if rain, ok := w.Rain.Volume("1h"); ok {
+		fmt.Fprintf(b, "Rain: %.2f mm (1h)\n", rain)
+	}
+	if snow, ok := w.Snow.Volume("1h"); ok {
+		fmt.Fprintf(b, "Snow: %.2f mm (1h)\n", snow)
+	}
+	fmt.Fprintf(b, "Sunset: %02d:%02d\n", sunset.Hour(), sunset.Minute())
+	fmt.Fprintf(b, "Sunrise: %02d:%02d\n", sunrise.Hour(), sunrise.Minute())
+	fmt.Fprintf(b, "Lookup: %s\n", opts.Lookup.Details())
+	return b.Flush()
+}
+
+func (p *PrettyOutputWriter) RenderForecast(out io.Writer, f *ForecastResponse, opts RenderOptions) error {
+	b := bufio.NewWriter(out)
+	tempSign, speedSign := opts.UnitSigns()
+	zone := opts.In(time.FixedZone(f.City.Name, f.City.Timezone))
+
+	fmt.Fprintf(b, "Forecast for %s:\n", p.placeName(f.City.Name, opts.Lookup))
+	p.printStale(b, opts.Response)
+
+	tw := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Time\tWeather\tTemp\tWind\tHumidity\tPrecip.")
+	for _, item := range f.List {
+		fmt.Fprintln(tw, p.forecastRow(item, zone, tempSign, speedSign))
+	}
+	tw.Flush()
+
+	fmt.Fprintf(b, "Lookup: %s\n", opts.Lookup.Details())
+	return b.Flush()
+}
+
+func (p *PrettyOutputWriter) RenderDaily(out io.Writer, d *DailyForecast, opts RenderOptions) error {
+	b := bufio.NewWriter(out)
+	tempSign, speedSign := opts.UnitSigns()
+
+	fmt.Fprintf(b, "Daily forecast for %s:\n", p.placeName(d.City.Name, opts.Lookup))
+	p.printStale(b, opts.Response)
+
+	tw := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Date\tWeather\tMin/Max\tRain\tSnow\tWind\tGusts\tPrecip.")
+	for _, day := range d.Days {
+		fmt.Fprintln(tw, p.dailyRow(day, tempSign, speedSign))
+	}
+	tw.Flush()
+
+	fmt.Fprintf(b, "Lookup: %s\n", opts.Lookup.Details())
+	return b.Flush()
+}
+
+// RenderLocations renders the locations in one table in their order. A failed
+// location is a row marked as an error with the cells of the header, so the
+// columns stay aligned. The errors and the stale locations are listed below.
+func (p *PrettyOutputWriter) RenderLocations(out io.Writer, results []LocationResult, opts RenderOptions) error {
+	b := bufio.NewWriter(out)
+	tempSign, speedSign := opts.UnitSigns()
+
+	tw := tabwriter.NewWriter(b, 0, 0, 2, ' ', 0)
+	var header []string
+	switch opts.Command {
+	case "forecast":
+		header = []string{"Location", "Time", "Weather", "Temp", "Wind", "Humidity", "Precip."}
+	case "daily":
+		header = []string{"Location", "Date", "Weather", "Min/Max", "Rain", "Snow", "Wind", "Gusts", "Precip."}
+	default:
+		header = []string{"Location", "Weather", "Temp", "Wind", "Humidity", "Pressure"}
+	}
+	fmt.Fprintln(tw, strings.Join(header, "\t"))
+
+	for _, result := range results {
+		switch {
+		case result.Err != nil:
+			cells := []string{result.Name(), "error"}
+			for len(cells) < len(header) {
+				cells = append(cells, "-")
+			}
+			fmt.Fprintln(tw, strings.Join(cells, "\t"))
+		case result.Weather != nil:
+			w := result.Weather
+			wind := "-"
+			if w.Wind != nil && w.Wind.Speed > 0 {
+				wind = fmt.Sprintf("%.1f %s (%s)", w.Wind.Speed, speedSign, WindDirection(w.Wind.Deg))
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%.0f%s\t%s\t%d%%\t%.0f hPa\n",
+				result.Name(),
+				w.Description(),
+				w.Main.Temp, tempSign,
+				wind,
+				w.Main.Humidity,
+				w.Main.Pressure,
+			)
+		case result.Forecast != nil:
+			zone := opts.In(time.FixedZone(result.Forecast.City.Name, result.Forecast.City.Timezone))
+			for _, item := range result.Forecast.List {
+				fmt.Fprintf(tw, "%s\t%s\n", result.Name(), p.forecastRow(item, zone, tempSign, speedSign))
+			}
+		case result.Daily != nil:
+			for _, day := range result.Daily.Days {
+				fmt.Fprintf(tw, "%s\t%s\n", result.Name(), p.dailyRow(day, tempSign, speedSign))
+			}
+		}
+	}
+	tw.Flush()
+
+	for _, result := range results {
+		if result.Err != nil {
+			fmt.Fprintf(b, "Error: %s: %s\n", result.Name(), result.Err)
+		}
+	}
+	for _, result := range results {
+		if result.Response != nil && result.Response.Stale {
+			fmt.Fprintf(b, "Stale: %s, the API is not reachable, data from %s\n", result.Name(), FormatAge(result.Response.Age()))
+		}
+	}
+	return b.Flush()
+}
+
+func (p *PrettyOutputWriter) forecastRow(item goopenweathermapapi.ForecastItem, zone *time.Location, tempSign, speedSign string) string {
+	return fmt.Sprintf("%s\t%s\t%.0f%s\t%.1f %s (%s)\t%d%%\t%.0f%%",
+		time.Unix(item.Dt, 0).In(zone).Format("Mon 01-02 15:04"),
+		item.Description(),
+		item.Main.Temp, tempSign,
+		item.Wind.Speed, speedSign, WindDirection(item.Wind.Deg),
+		item.Main.Humidity,
+		item.Pop*100,
+	)
+}
+
+func (p *PrettyOutputWriter) dailyRow(day DailySummary, tempSign, speedSign string) string {
+	return fmt.Sprintf("%s\t%s\t%.0f/%.0f%s\t%.1f mm\t%.1f mm\t%.1f %s\t%.1f %s\t%.0f%%",
+		day.Date,
+		day.Description,
+		day.TempMin, day.TempMax, tempSign,
+		day.Rain,
+		day.Snow,
+		day.WindMax, speedSign,
+		day.GustMax, speedSign,
+		day.PopMax*100,
+	)
+}
+
+// placeName is the place of the coordinates with the city name of the response,
+// the nearest station, if they differ.
+func (p *PrettyOutputWriter) placeName(city string, lookup Lookup) string {
+	place := lookup.Place
+	if place == "" {
+		return city
+	}
+	if city == "" || strings.HasPrefix(place, city+",") {
+		return place
+	}
+	return fmt.Sprintf("%s (nearest station %s)", place, city)
+}
+
+func (p *PrettyOutputWriter) printStale(out io.Writer, response *Response) {
+	if response != nil && response.Stale {
+		fmt.Fprintf(out, "Stale: the API is not reachable, data from %s\n", FormatAge(response.Age()))
+	}
+}
+
+// RenderError logs the error, see logError.
+func (p *PrettyOutputWriter) RenderError(out io.Writer, err error, opts RenderOptions) error {
+	return logError(out, err)
 }
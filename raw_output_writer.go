@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+func init() {
+	RegisterFormat(OutputFormat{
+		Name:        "raw",
+		Description: "the response body of the API as it was returned or cached",
+		New:         func() OutputWriterInterface { return &RawOutputWriter{Status: os.Stderr} },
+	})
+}
+
+// RawOutputWriter prints the response bodies of the API byte for byte, or
+// indented with --raw-indent. Where each body came from, the API or the cache,
+// is written to Status, so the bodies on out stay untouched.
+type RawOutputWriter struct {
+	Status io.Writer
+}
+
+func (r *RawOutputWriter) Render(out io.Writer, w *WeatherResponse, opts RenderOptions) error {
+	return r.print(out, "", opts.Response, opts)
+}
+
+func (r *RawOutputWriter) RenderForecast(out io.Writer, f *ForecastResponse, opts RenderOptions) error {
+	return r.print(out, "", opts.Response, opts)
+}
+
+// RenderDaily prints the body of the forecast, the days are not in the API
+// response.
+func (r *RawOutputWriter) RenderDaily(out io.Writer, d *DailyForecast, opts RenderOptions) error {
+	return r.print(out, "", opts.Response, opts)
+}
+
+// RenderBody prints the body of a failed request, which could not be decoded
+// or is an error of the API.
+func (r *RawOutputWriter) RenderBody(out io.Writer, opts RenderOptions) error {
+	return r.print(out, "", opts.Response, opts)
+}
+
+// RenderLocations prints the bodies of the locations in their order, one per
+// line unless they are indented. The errors of the failed locations are
+// written to Status, their bodies are printed if there are any.
+func (r *RawOutputWriter) RenderLocations(out io.Writer, results []LocationResult, opts RenderOptions) error {
+	for _, result := range results {
+		if result.Err != nil {
+			r.status("%s: %s", result.Name(), result.Err)
+			if result.Response == nil {
+				continue
+			}
+		}
+		if err := r.print(out, result.Name()+": ", result.Response, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RenderError logs the error, see logError.
+func (r *RawOutputWriter) RenderError(out io.Writer, err error, opts RenderOptions) error {
+	return logError(out, err)
+}
+
+func (r *RawOutputWriter) print(out io.Writer, prefix string, response *Response, opts RenderOptions) error {
+	if response == nil {
+		return fmt.Errorf("%sno response body", prefix)
+	}
+	r.status("%s%s", prefix, describeResponse(response, opts))
+
+	body := bytes.TrimRight(response.Body, "\n")
+	if opts.RawIndent {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, body, "", "  "); err == nil {
+			body = indented.Bytes()
+		}
+	}
+
+	b := bufio.NewWriter(out)
+	b.Write(body)
+	b.WriteByte('\n')
+	return b.Flush()
+}
+
+func (r *RawOutputWriter) status(format string, a ...interface{}) {
+	if r.Status != nil {
+		fmt.Fprintf(r.Status, format+"\n", a...)
+	}
+}
+
+// describeResponse tells the cache key of the response and whether it came
+// from the API or from the cache and when.
+func describeResponse(response *Response, opts RenderOptions) string {
+	fetchedAt := response.FetchedAt.In(opts.In(time.Local)).Format(time.RFC3339)
+	switch {
+	case response.StatusCode != 0:
+		return fmt.Sprintf("%s: error of the API at %s (HTTP %d)", response.Key, fetchedAt, response.StatusCode)
+	case response.Stale:
+		return fmt.Sprintf("%s: stale cache entry fetched at %s (%s), the API is not reachable", response.Key, fetchedAt, FormatAge(response.Age()))
+	case response.Cached:
+		return fmt.Sprintf("%s: cache entry fetched at %s (%s)", response.Key, fetchedAt, FormatAge(response.Age()))
+	}
+	return fmt.Sprintf("%s: fetched from the API at %s", response.Key, fetchedAt)
+}
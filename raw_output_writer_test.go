@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/belovai/goweather/goopenweathermapapi"
+)
+
+func TestRawOutputWriter(t *testing.T) {
+	var status, out bytes.Buffer
+	writer := &RawOutputWriter{Status: &status}
+
+	response := &Response{
+		Key:       "weather|city name london,gb|metric|",
+		Body:      []byte(`{"name":"London","extra":{"a":1}}`),
+		FetchedAt: time.Date(2018, 11, 3, 9, 0, 0, 0, time.UTC),
+		Cached:    true,
+	}
+	opts := RenderOptions{Timezone: time.UTC, Response: response}
+
+	if err := writer.Render(&out, &WeatherResponse{}, opts); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != `{"name":"London","extra":{"a":1}}`+"\n" {
+		t.Error("Error in raw output: " + out.String())
+	}
+	if !strings.HasPrefix(status.String(), "weather|city name london,gb|metric|: cache entry fetched at 2018-11-03T09:00:00Z (") {
+		t.Error("Error in raw status: " + status.String())
+	}
+
+	opts.RawIndent = true
+	out.Reset()
+	if err := writer.Render(&out, &WeatherResponse{}, opts); err != nil || !strings.Contains(out.String(), "\n  \"extra\": {\n    \"a\": 1\n  }\n}\n") {
+		t.Error("Error in indented raw output: " + out.String())
+	}
+
+	results := []LocationResult{
+		{Target: Target{Location: "home"}, Response: response},
+		{Target: Target{Location: "cabin"}, Err: &UsageError{Message: "failed"}},
+	}
+	status.Reset()
+	out.Reset()
+	opts.RawIndent = false
+	if err := writer.RenderLocations(&out, results, opts); err != nil || strings.Count(out.String(), "\n") != 1 {
+		t.Error("Error in raw locations: " + out.String())
+	}
+	if !strings.HasPrefix(status.String(), "home: weather|") || !strings.HasSuffix(status.String(), "cabin: failed\n") {
+		t.Error("Error in raw locations status: " + status.String())
+	}
+}
+
+func TestRawErrorBody(t *testing.T) {
+	var status, out bytes.Buffer
+	writer := &RawOutputWriter{Status: &status}
+
+	apiError := &goopenweathermapapi.Error{Kind: goopenweathermapapi.ErrAPI, StatusCode: 401, Body: []byte(`{"cod":401,"message":"Invalid API key"}`)}
+	response := ErrorResponse("weather|city name london,gb|metric|", apiError)
+	if response == nil || response.StatusCode != 401 {
+		t.Fatal("Error in error response")
+	}
+	if ErrorResponse("key", &UsageError{Message: "failed"}) != nil {
+		t.Error("Error in error response without body")
+	}
+
+	opts := RenderOptions{Timezone: time.UTC, Response: response}
+	if err := writer.RenderBody(&out, opts); err != nil || out.String() != `{"cod":401,"message":"Invalid API key"}`+"\n" {
+		t.Error("Error in raw error body: " + out.String())
+	}
+	if !strings.Contains(status.String(), "error of the API at") || !strings.HasSuffix(status.String(), "(HTTP 401)\n") {
+		t.Error("Error in raw error status: " + status.String())
+	}
+
+	results := []LocationResult{
+		{Target: Target{Location: "home"}, Response: &Response{Body: []byte(`{"main":"broken"}`)}, Err: &UsageError{Message: "can not decode"}},
+	}
+	out.Reset()
+	if err := writer.RenderLocations(&out, results, opts); err != nil || out.String() != `{"main":"broken"}`+"\n" {
+		t.Error("Error in raw undecodable location: " + out.String())
+	}
+}
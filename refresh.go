@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+)
+
+// backgroundRefreshEnv is set for the process started by StartRefresh, it is
+// the newline separated list of the cache keys to refresh.
+const backgroundRefreshEnv = "GOWEATHER_BACKGROUND_REFRESH"
+
+// BackgroundRefreshKeys returns the keys this process refreshes if it was
+// started by StartRefresh, or nil.
+func BackgroundRefreshKeys() map[string]bool {
+	value := os.Getenv(backgroundRefreshEnv)
+	if value == "" {
+		return nil
+	}
+	keys := map[string]bool{}
+	for _, key := range strings.Split(value, "\n") {
+		keys[key] = true
+	}
+	return keys
+}
+
+// RefreshQueue collects the expired and missing cache keys of a cache-only
+// run. Each key is locked when it is queued, so concurrent prompts refresh it
+// only once, and StartRefresh refreshes all of them in one process.
+type RefreshQueue struct {
+	mu    sync.Mutex
+	cache *Cache
+	keys  []string
+}
+
+// refreshQueue is the queue of the run, the cached clients of all requests
+// add to it.
+var refreshQueue = &RefreshQueue{}
+
+// Add locks the key and queues it, unless another process holds its lock.
+func (q *RefreshQueue) Add(cache *Cache, key string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for _, queued := range q.keys {
+		if queued == key {
+			return
+		}
+	}
+	if !cache.Lock(key) {
+		return
+	}
+	q.cache = cache
+	q.keys = append(q.keys, key)
+}
+
+// StartRefresh starts goweather again with the same arguments in a detached
+// process, which refreshes the queued keys and releases their locks. The
+// locks are released here if the process can not be started.
+func (q *RefreshQueue) StartRefresh() {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	keys := q.keys
+	q.keys = nil
+	if len(keys) == 0 {
+		return
+	}
+	unlock := func() {
+		for _, key := range keys {
+			q.cache.Unlock(key)
+		}
+	}
+
+	executable, err := os.Executable()
+	if err != nil {
+		unlock()
+		return
+	}
+
+	cmd := exec.Command(executable, os.Args[1:]...)
+	cmd.Env = append(os.Environ(), backgroundRefreshEnv+"="+strings.Join(keys, "\n"))
+	detach(cmd)
+
+	if err := cmd.Start(); err != nil {
+		unlock()
+		return
+	}
+	cmd.Process.Release()
+}
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/pborman/getopt/v2"
+)
+
+// Setting is the effective value of an option and where it came from.
+type Setting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+}
+
+const (
+	SourceFlag    = "flag"
+	SourceDefault = "default"
+)
+
+// settingEnvs are the options which can be set by environment variables and
+// in the config file, with the name of their environment variable.
+var settingEnvs = []struct {
+	Name string
+	Env  string
+}{
+	{"appid", "GOWEATHER_APPID"},
+	{"units", "GOWEATHER_UNITS"},
+	{"lang", "GOWEATHER_LANG"},
+	{"format", "GOWEATHER_FORMAT"},
+	{"json-schema", "GOWEATHER_JSON_SCHEMA"},
+	{"delimiter", "GOWEATHER_DELIMITER"},
+	{"api-url", "GOWEATHER_API_URL"},
+	{"timeout", "GOWEATHER_TIMEOUT"},
+	{"retries", "GOWEATHER_RETRIES"},
+	{"proxy", "GOWEATHER_PROXY"},
+	{"ca-cert", "GOWEATHER_CA_CERT"},
+	{"cache-ttl", "GOWEATHER_CACHE_TTL"},
+	{"max-stale", "GOWEATHER_MAX_STALE"},
+	{"workers", "GOWEATHER_WORKERS"},
+	{"resolve-city", "GOWEATHER_RESOLVE_CITY"},
+	{"timezone", "GOWEATHER_TIMEZONE"},
+	{"template", "GOWEATHER_TEMPLATE"},
+	{"template-file", "GOWEATHER_TEMPLATE_FILE"},
+}
+
+// lookupEnvs are the lookup options with the name of their environment variable.
+var lookupEnvs = []struct {
+	Name string
+	Env  string
+}{
+	{"city", "GOWEATHER_CITY"},
+	{"id", "GOWEATHER_ID"},
+	{"lat", "GOWEATHER_LAT"},
+	{"lon", "GOWEATHER_LON"},
+	{"at", "GOWEATHER_AT"},
+	{"airport", "GOWEATHER_AIRPORT"},
+	{"zip", "GOWEATHER_ZIP"},
+}
+
+// configKeys are the keys allowed at the top of the config file.
+func configKeys() []string {
+	keys := []string{"location"}
+	for _, setting := range settingEnvs {
+		keys = append(keys, setting.Name)
+	}
+	for _, lookup := range lookupEnvs {
+		keys = append(keys, lookup.Name)
+	}
+	return keys
+}
+
+func envSource(env string) string {
+	return "env " + env
+}
+
+func (c *Config) source(location string) string {
+	if location == "" {
+		return "config " + c.Path
+	}
+	return fmt.Sprintf("config %s [location %s]", c.Path, location)
+}
+
+// ConfigPath returns the path of the config file and whether it was set
+// explicitly by --config or GOWEATHER_CONFIG. It is empty if there is no
+// config directory.
+func ConfigPath() (string, bool) {
+	if getopt.IsSet("config") {
+		return *ConfigFile, true
+	}
+	if path := os.Getenv("GOWEATHER_CONFIG"); path != "" {
+		return path, true
+	}
+	path, _ := DefaultConfigPath()
+	return path, false
+}
+
+// LookupArg is a lookup option given on the command line.
+type LookupArg struct {
+	Name  string
+	Value string
+}
+
+// LookupArgs are the lookup options of the command line in the given order.
+var LookupArgs []LookupArg
+
+// lookupFlag is a repeatable lookup option, its values are recorded in
+// LookupArgs so that the order of the locations is kept.
+type lookupFlag string
+
+func (f lookupFlag) Set(value string, opt getopt.Option) error {
+	LookupArgs = append(LookupArgs, LookupArg{Name: string(f), Value: value})
+	return nil
+}
+
+func (f lookupFlag) String() string {
+	return ""
+}
+
+// ResolveTargets picks the locations to fetch. Lookup options given on the
+// command line take precedence over the GOWEATHER_* environment variables,
+// which take precedence over the config file. On the command line the lookup
+// options and --location can be repeated, the locations are fetched in the
+// given order. In the environment and in the config file the lookup options
+// win over a named location.
+func ResolveTargets(config *Config) ([]Target, Setting, error) {
+	if len(LookupArgs) > 0 {
+		targets, err := config.argTargets(LookupArgs)
+		return newTargetsSetting(targets, err, SourceFlag)
+	}
+
+	env := LookupValues{
+		City:    os.Getenv("GOWEATHER_CITY"),
+		ID:      os.Getenv("GOWEATHER_ID"),
+		Lat:     os.Getenv("GOWEATHER_LAT"),
+		Lon:     os.Getenv("GOWEATHER_LON"),
+		At:      os.Getenv("GOWEATHER_AT"),
+		Airport: os.Getenv("GOWEATHER_AIRPORT"),
+		Zip:     os.Getenv("GOWEATHER_ZIP"),
+	}
+	if len(env.kinds()) > 0 {
+		var names []string
+		for _, lookup := range lookupEnvs {
+			if os.Getenv(lookup.Env) != "" {
+				names = append(names, lookup.Env)
+			}
+		}
+		targets, err := newTargets(env, "")
+		return newTargetsSetting(targets, err, envSource(strings.Join(names, ", ")))
+	}
+	if names := os.Getenv("GOWEATHER_LOCATION"); names != "" {
+		targets, err := config.namedTargets(splitNames(names))
+		return newTargetsSetting(targets, err, envSource("GOWEATHER_LOCATION"))
+	}
+
+	file := LookupValues{
+		City:    config.Defaults["city"],
+		ID:      config.Defaults["id"],
+		Lat:     config.Defaults["lat"],
+		Lon:     config.Defaults["lon"],
+		At:      config.Defaults["at"],
+		Airport: config.Defaults["airport"],
+		Zip:     config.Defaults["zip"],
+	}
+	if len(file.kinds()) > 0 {
+		targets, err := newTargets(file, "")
+		return newTargetsSetting(targets, err, config.source(""))
+	}
+	if names := config.Defaults["location"]; names != "" {
+		targets, err := config.namedTargets(splitNames(names))
+		return newTargetsSetting(targets, err, config.source(""))
+	}
+
+	_, err := NewLookup(LookupValues{})
+	return nil, Setting{Name: "location", Value: "(not set)", Source: SourceDefault}, err
+}
+
+// newTargetsSetting adds the source to the error or builds the setting of the
+// targets.
+func newTargetsSetting(targets []Target, err error, source string) ([]Target, Setting, error) {
+	if err != nil {
+		return nil, Setting{}, fmt.Errorf("%s (from %s)", err, source)
+	}
+
+	values := make([]string, 0, len(targets))
+	for _, target := range targets {
+		values = append(values, target.String())
+	}
+	return targets, Setting{Name: "location", Value: strings.Join(values, "; "), Source: source}, nil
+}
+
+func newTargets(values LookupValues, location string) ([]Target, error) {
+	lookup, err := NewLookup(values)
+	if err != nil {
+		return nil, err
+	}
+	return []Target{{Lookup: lookup, Location: location}}, nil
+}
+
+// argTargets returns the targets of the lookup options of the command line,
+// --lat and --lon are paired in the given order.
+func (c *Config) argTargets(args []LookupArg) ([]Target, error) {
+	var targets []Target
+	// The repeated --lat and --lon are paired in their order, a location is
+	// added when both of its values are given.
+	var lats, lons []string
+	latCount, lonCount := 0, 0
+
+	for _, arg := range args {
+		var values LookupValues
+		switch arg.Name {
+		case "location":
+			named, err := c.namedTargets([]string{arg.Value})
+			if err != nil {
+				return nil, err
+			}
+			targets = append(targets, named...)
+			continue
+		case "lat":
+			lats = append(lats, arg.Value)
+			latCount++
+		case "lon":
+			lons = append(lons, arg.Value)
+			lonCount++
+		case "city":
+			values.City = arg.Value
+		case "id":
+			values.ID = arg.Value
+		case "at":
+			values.At = arg.Value
+		case "airport":
+			values.Airport = arg.Value
+		case "zip":
+			values.Zip = arg.Value
+		}
+
+		if arg.Name == "lat" || arg.Name == "lon" {
+			if len(lats) == 0 || len(lons) == 0 {
+				continue
+			}
+			values = LookupValues{Lat: lats[0], Lon: lons[0]}
+			lats, lons = lats[1:], lons[1:]
+		}
+
+		target, err := newTargets(values, "")
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, target...)
+	}
+
+	if latCount != lonCount {
+		return nil, &UsageError{Message: fmt.Sprintf("--lat and --lon must be used together, got %d --lat and %d --lon", latCount, lonCount)}
+	}
+
+	return targets, nil
+}
+
+// namedTargets returns the targets of named locations and groups.
+func (c *Config) namedTargets(names []string) ([]Target, error) {
+	var targets []Target
+	for _, name := range names {
+		locations := []string{name}
+		if group, ok := c.Groups[name]; ok {
+			locations = group
+		}
+
+		for _, location := range locations {
+			values, ok := c.Locations[location]
+			if !ok {
+				return nil, fmt.Errorf("unknown location %s, it is not defined in %s", location, c.Path)
+			}
+			target, err := newTargets(LookupValues{
+				City:    values["city"],
+				ID:      values["id"],
+				Lat:     values["lat"],
+				Lon:     values["lon"],
+				At:      values["at"],
+				Airport: values["airport"],
+				Zip:     values["zip"],
+			}, location)
+			if err != nil {
+				return nil, fmt.Errorf("%s in %s", err, c.source(location))
+			}
+			targets = append(targets, target...)
+		}
+	}
+	return targets, nil
+}
+
+// splitNames splits a comma separated list of location names.
+func splitNames(names string) []string {
+	var split []string
+	for _, name := range strings.Split(names, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			split = append(split, name)
+		}
+	}
+	return split
+}
+
+// locationSetting returns the value of a setting of the named locations of the
+// targets and the names of the locations which set it. The targets are fetched
+// with the same settings, so they must agree on it, a target without the
+// setting uses the global value.
+func (c *Config) locationSetting(name string, targets []Target) (string, string, error) {
+	var value string
+	var locations, values []string
+	mixed := false
+	for i, target := range targets {
+		v := c.Locations[target.Location][name]
+		if i > 0 && v != value {
+			mixed = true
+		}
+		value = v
+
+		if v == "" {
+			v = "global"
+		} else if !contains(locations, target.Location) {
+			locations = append(locations, target.Location)
+		}
+		location := target.Location
+		if location == "" {
+			location = target.Lookup.String()
+		}
+		values = append(values, fmt.Sprintf("%s: %s", location, v))
+	}
+
+	if mixed {
+		return "", "", &UsageError{Message: fmt.Sprintf("the locations are fetched with the same %s, but they have different ones: %s; fetch them separately or set --%s",
+			name, strings.Join(values, ", "), name)}
+	}
+	return value, strings.Join(locations, ", "), nil
+}
+
+// ApplySettings sets the options which were not given on the command line
+// from the environment variables, the named locations of the targets and the
+// config file, in this order. It returns the effective settings.
+func ApplySettings(config *Config, targets []Target) ([]Setting, error) {
+	settings := make([]Setting, 0, len(settingEnvs))
+
+	for _, s := range settingEnvs {
+		opt := getopt.Lookup(s.Name)
+		setting := Setting{Name: s.Name, Value: opt.String(), Source: SourceDefault}
+		value, location, err := config.locationSetting(s.Name, targets)
+
+		switch {
+		case opt.Seen():
+			setting.Source = SourceFlag
+		case os.Getenv(s.Env) != "":
+			setting = Setting{Name: s.Name, Value: os.Getenv(s.Env), Source: envSource(s.Env)}
+		case err != nil:
+			return nil, err
+		case value != "":
+			setting = Setting{Name: s.Name, Value: value, Source: config.source(location)}
+		case config.Defaults[s.Name] != "":
+			setting = Setting{Name: s.Name, Value: config.Defaults[s.Name], Source: config.source("")}
+		}
+
+		if setting.Source != SourceFlag && setting.Source != SourceDefault {
+			if err := opt.Value().Set(setting.Value, opt); err != nil {
+				return nil, &UsageError{Message: fmt.Sprintf("invalid %s from %s: %s", s.Name, setting.Source, err)}
+			}
+		}
+
+		settings = append(settings, setting)
+	}
+
+	return settings, nil
+}
+
+// ShowConfig prints the effective settings and where each came from.
+func ShowConfig(config *Config, settings []Setting) error {
+	printer, err := DataPrinter("config show")
+	if err != nil {
+		return err
+	}
+
+	for i := range settings {
+		if settings[i].Name == "appid" {
+			settings[i].Value = maskSecret(settings[i].Value)
+		}
+	}
+
+	if printer != nil {
+		return printer(os.Stdout, map[string]interface{}{
+			"config":    config.Path,
+			"settings":  settings,
+			"locations": config.LocationNames(),
+			"groups":    config.Groups,
+		})
+	}
+
+	fmt.Printf("Config file: %s\n", config.Path)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "Setting\tValue\tSource")
+	for _, setting := range settings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", setting.Name, setting.Value, setting.Source)
+	}
+	tw.Flush()
+	fmt.Printf("Locations: %s\n", strings.Join(config.LocationNames(), ", "))
+	for _, name := range config.GroupNames() {
+		fmt.Printf("Group %s: %s\n", name, strings.Join(config.Groups[name], ", "))
+	}
+	return nil
+}
+
+func maskSecret(secret string) string {
+	if len(secret) <= 4 {
+		return strings.Repeat("*", len(secret))
+	}
+	return strings.Repeat("*", len(secret)-4) + secret[len(secret)-4:]
+}
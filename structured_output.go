@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+)
+
+// The yaml, toml, csv and tsv formats print the records of the JSON output,
+// see Record. The records are marshaled to JSON and decoded to an ordered
+// tree, so the fields are in the order of the JSON output and the formats can
+// not drift from it.
+
+// orderedField is a field of a JSON object.
+type orderedField struct {
+	Key   string
+	Value interface{}
+}
+
+// orderedObject is a JSON object in the order of its fields. The other values
+// of the tree are []interface{}, string, json.Number, bool and nil.
+type orderedObject []orderedField
+
+// errorRecord is the record of an error of the yaml and toml formats.
+type errorRecord struct {
+	Version int                    `json:"version"`
+	Error   map[string]interface{} `json:"error"`
+}
+
+func newErrorRecord(err error) errorRecord {
+	return errorRecord{Version: JsonSchemaVersion, Error: errorFields(err)}
+}
+
+// orderedJSON returns the ordered tree of the JSON of v.
+func orderedJSON(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decodeOrdered(decoder)
+}
+
+func decodeOrdered(decoder *json.Decoder) (interface{}, error) {
+	token, err := decoder.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	switch token {
+	case json.Delim('{'):
+		object := orderedObject{}
+		for decoder.More() {
+			key, err := decoder.Token()
+			if err != nil {
+				return nil, err
+			}
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			object = append(object, orderedField{Key: key.(string), Value: value})
+		}
+		_, err = decoder.Token()
+		return object, err
+	case json.Delim('['):
+		array := []interface{}{}
+		for decoder.More() {
+			value, err := decodeOrdered(decoder)
+			if err != nil {
+				return nil, err
+			}
+			array = append(array, value)
+		}
+		_, err = decoder.Token()
+		return array, err
+	}
+	return token, nil
+}
+
+// quoteString quotes s with the escapes of JSON, which are valid in the
+// double quoted strings of YAML and TOML as well.
+func quoteString(s string) string {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(s)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// bareKey are the keys which can be written without quotes in YAML and TOML.
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// quoteKey quotes the keys which are not bare keys, e.g. a group name with a
+// dot or a space.
+func quoteKey(key string) string {
+	if bareKey.MatchString(key) {
+		return key
+	}
+	return quoteString(key)
+}
+
+// recordErrorColumns are the columns of the error of a failed location.
+var recordErrorColumns = []string{"code", "category", "message", "http_status", "retryable"}
+
+// RecordColumns returns the columns of the csv and tsv formats, the fields of
+// Record with the nested fields joined by dots, e.g. wind.speed. They are the
+// same for every command, so the header is stable.
+func RecordColumns() []string {
+	return typeColumns(reflect.TypeOf(Record{}), "")
+}
+
+func typeColumns(t reflect.Type, prefix string) []string {
+	var columns []string
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := prefix + strings.Split(field.Tag.Get("json"), ",")[0]
+
+		fieldType := field.Type
+		if fieldType.Kind() == reflect.Ptr {
+			fieldType = fieldType.Elem()
+		}
+		switch fieldType.Kind() {
+		case reflect.Struct:
+			columns = append(columns, typeColumns(fieldType, name+".")...)
+		case reflect.Map:
+			for _, key := range recordErrorColumns {
+				columns = append(columns, name+"."+key)
+			}
+		default:
+			columns = append(columns, name)
+		}
+	}
+	return columns
+}
+
+// flatten adds the scalars of the tree to values by their dotted paths, null
+// is empty. The arrays are left out, e.g. the candidates of an ambiguous city.
+func flatten(values map[string]string, path string, v interface{}) {
+	switch v := v.(type) {
+	case orderedObject:
+		for _, field := range v {
+			key := field.Key
+			if path != "" {
+				key = path + "." + key
+			}
+			flatten(values, key, field.Value)
+		}
+	case []interface{}:
+	case nil:
+		values[path] = ""
+	default:
+		values[path] = fmt.Sprint(v)
+	}
+}
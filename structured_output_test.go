@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestStructuredOutputWriters(t *testing.T) {
+	var forecast ForecastResponse
+	if err := json.Unmarshal([]byte(forecastJson), &forecast); err != nil {
+		t.Fatal(err)
+	}
+
+	results := []LocationResult{
+		{Target: Target{Location: "home"}, Forecast: &forecast},
+		{Target: Target{Location: "cabin"}, Err: &UsageError{Message: "no \"cabin\", sorry"}},
+	}
+	opts := RenderOptions{Units: "metric", Delimiter: ';'}
+
+	writer, _ := NewOutputWriter("yaml")
+	var out bytes.Buffer
+	if err := writer.RenderLocations(&out, results, opts); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"- version: 2\n  location: home\n", "\n  description: light rain\n", "\n    snow: null\n", "\n  error:\n    category: usage\n", "\n    message: \"no \\\"cabin\\\", sorry\"\n"} {
+		if !strings.Contains(out.String(), line) {
+			t.Error("Error in yaml output: " + line)
+		}
+	}
+
+	writer, _ = NewOutputWriter("toml")
+	out.Reset()
+	if err := writer.RenderLocations(&out, results, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), "[[records]]\nversion = 2\nlocation = \"home\"\n") || !strings.Contains(out.String(), "\n[records.wind]\nspeed = 4.1\n") || strings.Contains(out.String(), "snow") {
+		t.Error("Error in toml output: " + out.String())
+	}
+
+	writer, _ = NewOutputWriter("csv")
+	out.Reset()
+	if err := writer.RenderLocations(&out, results, opts); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	if len(lines) != 3 || lines[0] != strings.Join(RecordColumns(), ";") {
+		t.Fatal("Error in csv output: " + out.String())
+	}
+	if !strings.HasPrefix(lines[1], "2;home;London;GB;;51.5073;-0.1277;") || !strings.HasSuffix(lines[2], ";2;usage;\"no \"\"cabin\"\", sorry\";0;false") {
+		t.Error("Error in csv rows: " + out.String())
+	}
+
+	writer, _ = NewOutputWriter("tsv")
+	out.Reset()
+	if err := writer.RenderForecast(&out, &forecast, opts); err != nil || !strings.HasPrefix(out.String(), "version\tlocation\tcity\t") {
+		t.Error("Error in tsv output: " + out.String())
+	}
+}
+
+func TestRecordColumns(t *testing.T) {
+	columns := strings.Join(RecordColumns(), ",")
+	if !strings.HasPrefix(columns, "version,location,city,") || !strings.Contains(columns, ",wind.speed,wind.deg,") || !strings.Contains(columns, ",sun.sunrise.utc,sun.sunrise.local,") || !strings.HasSuffix(columns, ",error.http_status,error.retryable") {
+		t.Error("Error in record columns: " + columns)
+	}
+}
+
+func TestQuotedKeys(t *testing.T) {
+	v := map[string]interface{}{
+		"group eu.north": map[string]interface{}{"home town": 1, "temp_max": 2},
+		"#x":             "a",
+	}
+
+	var out bytes.Buffer
+	if err := (&YamlOutputWriter{}).print(&out, v); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "\"#x\": a\n\"group eu.north\":\n  \"home town\": 1\n  temp_max: 2\n" {
+		t.Error("Error in yaml keys: " + out.String())
+	}
+
+	out.Reset()
+	if err := (&TomlOutputWriter{}).print(&out, v); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "\"#x\" = \"a\"\n\n[\"group eu.north\"]\n\"home town\" = 1\ntemp_max = 2\n" {
+		t.Error("Error in toml keys: " + out.String())
+	}
+}
@@ -0,0 +1,412 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/belovai/goweather/goopenweathermapapi"
+	"github.com/pborman/getopt/v2"
+)
+
+func init() {
+	RegisterFormat(OutputFormat{
+		Name:        "template",
+		Description: "text/template of --template or --template-file",
+		New:         func() OutputWriterInterface { return &TemplateOutputWriter{} },
+	})
+}
+
+// TemplateData is the data of --format=template. The template is executed for
+// the current weather of each location, for each slot of the forecast and for
+// each day of the daily forecast. The fields which do not apply are zero.
+type TemplateData struct {
+	// Location is the named location, the place or the city name.
+	Location string
+	City     string
+	Country  string
+	// Place is the place name of the coordinates or the airport.
+	Place string
+	// Lookup describes the lookup, e.g. "city name London,gb".
+	Lookup string
+	Lat    float64
+	Lon    float64
+
+	// Time is the time of the observation or the forecast slot, or the start
+	// of the day in the timezone of the output.
+	Time time.Time
+	// Date is the day of the daily forecast, YYYY-MM-DD.
+	Date        string
+	Main        string
+	Description string
+	// Icon is the icon code of the API, e.g. 10d.
+	Icon string
+
+	Temp      float64
+	FeelsLike float64
+	TempMin   float64
+	TempMax   float64
+	Pressure  float64
+	Humidity  int
+	Clouds    int
+	WindSpeed float64
+	WindDeg   float64
+	WindGust  float64
+	// Rain and Snow are mm in the last hour of the current weather, in the
+	// 3 hours of a forecast slot or in the day of the daily forecast.
+	Rain float64
+	Snow float64
+	// Pop is the probability of precipitation between 0 and 1.
+	Pop float64
+
+	Sunrise time.Time
+	Sunset  time.Time
+
+	// Units are metric or imperial, TempUnit and SpeedUnit their signs.
+	Units     string
+	TempUnit  string
+	SpeedUnit string
+
+	// Stale is set if the API was not reachable and the data is from the
+	// cache, ObservedAt is the time it was fetched from the API.
+	Stale      bool
+	ObservedAt time.Time
+	// Error is the error of a failed location of several locations.
+	Error string
+}
+
+// TemplateOutputWriter executes the template of --template or
+// --template-file. A newline is added after each execution unless the
+// template ends with one.
+type TemplateOutputWriter struct {
+}
+
+// templateFuncs are the helper functions of the templates.
+func templateFuncs(opts RenderOptions) template.FuncMap {
+	tempSign, speedSign := opts.UnitSigns()
+	return template.FuncMap{
+		"temp": func(temp float64) string {
+			return fmt.Sprintf("%.0f%s", temp, tempSign)
+		},
+		"speed": func(speed float64) string {
+			return fmt.Sprintf("%.1f %s", speed, speedSign)
+		},
+		"compass": WindDirection,
+		"percent": func(ratio float64) string {
+			return fmt.Sprintf("%.0f%%", ratio*100)
+		},
+		"time": func(layout string, t time.Time) string {
+			return t.Format(layout)
+		},
+		"icon": WeatherIcon,
+	}
+}
+
+// ParseTemplate parses the template with the helper functions. The errors are
+// usage errors with the line and the column of the problem.
+func ParseTemplate(text string, opts RenderOptions) (*template.Template, error) {
+	tmpl, err := template.New("template").Funcs(templateFuncs(opts)).Parse(text)
+	if err != nil {
+		return nil, templateError(text, err)
+	}
+	return tmpl, nil
+}
+
+var (
+	templateExecError  = regexp.MustCompile(`^template: [^:]*:(\d+):(\d+): executing "[^"]*" at <[^>]*>: (.*)$`)
+	templateParseError = regexp.MustCompile(`^template: [^:]*:(\d+): (.*)$`)
+	templateQuoted     = regexp.MustCompile(`["<']([^"'>]+)["'>]`)
+)
+
+// templateError reformats an error of text/template with the line and the
+// column. The execution errors have the column, the column of a parse error is
+// where the quoted token of the message is on the line, or the first action
+// of the line.
+func templateError(text string, err error) error {
+	message := err.Error()
+	if m := templateExecError.FindStringSubmatch(message); m != nil {
+		line, _ := strconv.Atoi(m[1])
+		column, _ := strconv.Atoi(m[2])
+		return &UsageError{Message: fmt.Sprintf("template error at line %d, column %d: %s", line, column+1, m[3])}
+	}
+
+	m := templateParseError.FindStringSubmatch(message)
+	if m == nil {
+		return &UsageError{Message: "template error: " + message}
+	}
+	line, _ := strconv.Atoi(m[1])
+	message = m[2]
+
+	source := ""
+	if lines := strings.Split(text, "\n"); line >= 1 && line <= len(lines) {
+		source = lines[line-1]
+	}
+	column := strings.Index(source, "{{")
+	if q := templateQuoted.FindStringSubmatch(message); q != nil {
+		if i := strings.Index(source, q[1]); i >= 0 {
+			column = i
+		}
+	}
+	if column < 0 {
+		column = 0
+	}
+	return &UsageError{Message: fmt.Sprintf("template error at line %d, column %d: %s", line, column+1, message)}
+}
+
+// LoadTemplate returns the template of --template or the file of
+// --template-file. If both are set, the one given on the command line wins
+// over the environment and the config file.
+func LoadTemplate(text, file string) (string, error) {
+	if text != "" && file != "" {
+		switch {
+		case getopt.IsSet("template") && !getopt.IsSet("template-file"):
+			file = ""
+		case getopt.IsSet("template-file") && !getopt.IsSet("template"):
+			text = ""
+		default:
+			return "", &UsageError{Message: "--template and --template-file can not be used together"}
+		}
+	}
+	if file == "" {
+		return text, nil
+	}
+	content, err := os.ReadFile(file)
+	if err != nil {
+		return "", &UsageError{Message: fmt.Sprintf("invalid template file: %s", err)}
+	}
+	return string(content), nil
+}
+
+func (t *TemplateOutputWriter) Render(out io.Writer, w *WeatherResponse, opts RenderOptions) error {
+	return t.execute(out, opts, []TemplateData{t.weather(w, opts.Lookup, opts.Response, opts)})
+}
+
+func (t *TemplateOutputWriter) RenderForecast(out io.Writer, f *ForecastResponse, opts RenderOptions) error {
+	return t.execute(out, opts, t.forecast(f, opts.Lookup, opts.Response, opts))
+}
+
+func (t *TemplateOutputWriter) RenderDaily(out io.Writer, d *DailyForecast, opts RenderOptions) error {
+	return t.execute(out, opts, t.daily(d, opts.Lookup, opts.Response, opts))
+}
+
+// RenderLocations executes the template for the rows of all locations in
+// their order, Location is set in each of them.
+func (t *TemplateOutputWriter) RenderLocations(out io.Writer, results []LocationResult, opts RenderOptions) error {
+	var rows []TemplateData
+	for _, result := range results {
+		var location []TemplateData
+		switch {
+		case result.Err != nil:
+			location = []TemplateData{t.base(result.Target.Lookup, nil, opts)}
+			location[0].Error = result.Err.Error()
+		case result.Weather != nil:
+			location = []TemplateData{t.weather(result.Weather, result.Target.Lookup, result.Response, opts)}
+		case result.Forecast != nil:
+			location = t.forecast(result.Forecast, result.Target.Lookup, result.Response, opts)
+		case result.Daily != nil:
+			location = t.daily(result.Daily, result.Target.Lookup, result.Response, opts)
+		}
+
+		for _, row := range location {
+			row.Location = result.Name()
+			rows = append(rows, row)
+		}
+	}
+	return t.execute(out, opts, rows)
+}
+
+// RenderError logs the error, see logError.
+func (t *TemplateOutputWriter) RenderError(out io.Writer, err error, opts RenderOptions) error {
+	return logError(out, err)
+}
+
+func (t *TemplateOutputWriter) execute(out io.Writer, opts RenderOptions, rows []TemplateData) error {
+	if opts.Template == "" {
+		return &UsageError{Message: "--format=template needs --template or --template-file"}
+	}
+	tmpl, err := ParseTemplate(opts.Template, opts)
+	if err != nil {
+		return err
+	}
+
+	var b bytes.Buffer
+	for _, row := range rows {
+		if err := tmpl.Execute(&b, row); err != nil {
+			var execErr template.ExecError
+			if errors.As(err, &execErr) {
+				return templateError(opts.Template, err)
+			}
+			return err
+		}
+		if !strings.HasSuffix(opts.Template, "\n") {
+			b.WriteByte('\n')
+		}
+	}
+	_, err = b.WriteTo(out)
+	return err
+}
+
+// base is the data of the lookup and the response of every row.
+func (t *TemplateOutputWriter) base(lookup Lookup, response *Response, opts RenderOptions) TemplateData {
+	tempSign, speedSign := opts.UnitSigns()
+	data := TemplateData{
+		Place:     lookup.Place,
+		Lookup:    lookup.Details(),
+		Units:     opts.Units,
+		TempUnit:  tempSign,
+		SpeedUnit: speedSign,
+	}
+	if response != nil {
+		data.Stale = response.Stale
+		data.ObservedAt = response.FetchedAt.In(opts.In(time.Local))
+	}
+	return data
+}
+
+func (t *TemplateOutputWriter) weather(w *WeatherResponse, lookup Lookup, response *Response, opts RenderOptions) TemplateData {
+	zone := opts.In(w.Location())
+	data := t.base(lookup, response, opts)
+	data.Location = w.Name
+	if lookup.Place != "" {
+		data.Location = lookup.Place
+	}
+	data.City = w.Name
+	data.Country = w.Sys.Country
+	data.Lat = w.Coord.Lat
+	data.Lon = w.Coord.Lon
+	data.Time = time.Unix(w.Dt, 0).In(zone)
+	data.Date = data.Time.Format("2006-01-02")
+	if len(w.Weather) > 0 {
+		data.Main = w.Weather[0].Main
+		data.Icon = w.Weather[0].Icon
+	}
+	data.Description = w.Description()
+	data.Temp = w.Main.Temp
+	data.FeelsLike = w.Main.Temp
+	if w.Main.FeelsLike != nil {
+		data.FeelsLike = *w.Main.FeelsLike
+	}
+	data.TempMin = w.Main.TempMin
+	data.TempMax = w.Main.TempMax
+	data.Pressure = w.Main.Pressure
+	data.Humidity = w.Main.Humidity
+	if w.Clouds != nil {
+		data.Clouds = w.Clouds.All
+	}
+	if w.Wind != nil {
+		data.WindSpeed = w.Wind.Speed
+		data.WindDeg = w.Wind.Deg
+		data.WindGust = w.Wind.GustSpeed()
+	}
+	data.Rain, _ = w.Rain.Volume("1h")
+	data.Snow, _ = w.Snow.Volume("1h")
+	data.Sunrise = time.Unix(w.Sys.Sunrise, 0).In(zone)
+	data.Sunset = time.Unix(w.Sys.Sunset, 0).In(zone)
+	return data
+}
+
+func (t *TemplateOutputWriter) forecast(f *ForecastResponse, lookup Lookup, response *Response, opts RenderOptions) []TemplateData {
+	zone := opts.In(time.FixedZone(f.City.Name, f.City.Timezone))
+	rows := make([]TemplateData, 0, len(f.List))
+	for _, item := range f.List {
+		data := t.city(f.City, lookup, response, opts)
+		data.Time = time.Unix(item.Dt, 0).In(zone)
+		data.Date = data.Time.Format("2006-01-02")
+		if len(item.Weather) > 0 {
+			data.Main = item.Weather[0].Main
+			data.Icon = item.Weather[0].Icon
+		}
+		data.Description = item.Description()
+		data.Temp = item.Main.Temp
+		data.FeelsLike = item.Main.Temp
+		if item.Main.FeelsLike != nil {
+			data.FeelsLike = *item.Main.FeelsLike
+		}
+		data.TempMin = item.Main.TempMin
+		data.TempMax = item.Main.TempMax
+		data.Pressure = item.Main.Pressure
+		data.Humidity = item.Main.Humidity
+		if item.Clouds != nil {
+			data.Clouds = item.Clouds.All
+		}
+		data.WindSpeed = item.Wind.Speed
+		data.WindDeg = item.Wind.Deg
+		data.WindGust = item.Wind.GustSpeed()
+		data.Rain, _ = item.Rain.Volume("3h")
+		data.Snow, _ = item.Snow.Volume("3h")
+		data.Pop = item.Pop
+		rows = append(rows, data)
+	}
+	return rows
+}
+
+func (t *TemplateOutputWriter) daily(d *DailyForecast, lookup Lookup, response *Response, opts RenderOptions) []TemplateData {
+	cityZone := time.FixedZone(d.City.Name, d.City.Timezone)
+	rows := make([]TemplateData, 0, len(d.Days))
+	for _, day := range d.Days {
+		data := t.city(d.City, lookup, response, opts)
+		if start, err := time.ParseInLocation("2006-01-02", day.Date, cityZone); err == nil {
+			data.Time = start.In(opts.In(cityZone))
+		}
+		data.Date = day.Date
+		data.Main = day.Main
+		data.Description = day.Description
+		data.TempMin = day.TempMin
+		data.TempMax = day.TempMax
+		data.WindSpeed = day.WindMax
+		data.WindGust = day.GustMax
+		data.Rain = day.Rain
+		data.Snow = day.Snow
+		data.Pop = day.PopMax
+		rows = append(rows, data)
+	}
+	return rows
+}
+
+// city is the base of the forecast rows with the city of the forecast.
+func (t *TemplateOutputWriter) city(city goopenweathermapapi.ForecastCity, lookup Lookup, response *Response, opts RenderOptions) TemplateData {
+	zone := opts.In(time.FixedZone(city.Name, city.Timezone))
+	data := t.base(lookup, response, opts)
+	data.Location = city.Name
+	if lookup.Place != "" {
+		data.Location = lookup.Place
+	}
+	data.City = city.Name
+	data.Country = city.Country
+	data.Lat = city.Coord.Lat
+	data.Lon = city.Coord.Lon
+	if city.Sunrise != 0 {
+		data.Sunrise = time.Unix(city.Sunrise, 0).In(zone)
+		data.Sunset = time.Unix(city.Sunset, 0).In(zone)
+	}
+	return data
+}
+
+// weatherIcons are the icons of the weather groups of the API, by the first
+// two digits of the icon code and by the main weather.
+var weatherIcons = map[string]string{
+	"01d": "☀️", "01n": "🌙",
+	"02": "⛅", "03": "☁️", "04": "☁️", "09": "🌧️", "10": "🌦️", "11": "⛈️", "13": "❄️", "50": "🌫️",
+	"Clear": "☀️", "Clouds": "☁️", "Drizzle": "🌧️", "Rain": "🌧️", "Thunderstorm": "⛈️", "Snow": "❄️",
+	"Mist": "🌫️", "Smoke": "🌫️", "Haze": "🌫️", "Dust": "🌫️", "Fog": "🌫️", "Sand": "🌫️", "Ash": "🌫️",
+	"Squall": "💨", "Tornado": "🌪️",
+}
+
+// WeatherIcon returns an emoji of an icon code of the API like 10d or of a
+// main weather like Rain, or an empty string if it is not known.
+func WeatherIcon(code string) string {
+	if icon, ok := weatherIcons[code]; ok {
+		return icon
+	}
+	if len(code) == 3 {
+		return weatherIcons[code[:2]]
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTemplateOutputWriter(t *testing.T) {
+	var weather WeatherResponse
+	if err := json.Unmarshal([]byte(weatherJson), &weather); err != nil {
+		t.Fatal(err)
+	}
+
+	opts := RenderOptions{
+		Units:    "metric",
+		Timezone: time.UTC,
+		Lookup:   Lookup{Kind: LookupCity, City: "London,gb"},
+		Template: `{{.Location}}: {{temp .Temp}} {{speed .WindSpeed}} {{compass .WindDeg}} {{time "15:04" .Sunrise}}`,
+	}
+	writer, _ := NewOutputWriter("template")
+
+	var out bytes.Buffer
+	if err := writer.Render(&out, &weather, opts); err != nil {
+		t.Fatal(err)
+	}
+	if out.String() != "London: 12°C 4.1 m/s W 06:53\n" {
+		t.Error("Error in template output: " + out.String())
+	}
+
+	var forecast ForecastResponse
+	if err := json.Unmarshal([]byte(forecastJson), &forecast); err != nil {
+		t.Fatal(err)
+	}
+	opts.Template = "{{.Date}} {{icon .Icon}} {{percent .Pop}} {{.Rain}}\n"
+	out.Reset()
+	if err := writer.RenderForecast(&out, &forecast, opts); err != nil || out.String() != "2018-11-03 🌦️ 35% 0.25\n" {
+		t.Error("Error in template forecast: " + out.String())
+	}
+
+	opts.Template = "{{.Date}} {{icon .Main}} {{temp .TempMax}}"
+	out.Reset()
+	if err := writer.RenderDaily(&out, NewDailyForecast(&forecast), opts); err != nil || out.String() != "2018-11-03 🌧️ 11°C\n" {
+		t.Error("Error in template daily: " + out.String())
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	tests := []struct {
+		template string
+		message  string
+	}{
+		{"a\n  {{.Temp | nosuch}}", "template error at line 2, column 13: function \"nosuch\" not defined"},
+		{"x {{.Temp}", "template error at line 1, column 10: bad character U+007D '}'"},
+		{"{{if .Stale}}", "template error at line 1, column 1: unexpected EOF"},
+		{"x {{.Nope}}", "template error at line 1, column 5: can't evaluate field Nope in type main.TemplateData"},
+	}
+
+	var weather WeatherResponse
+	if err := json.Unmarshal([]byte(weatherJson), &weather); err != nil {
+		t.Fatal(err)
+	}
+	writer, _ := NewOutputWriter("template")
+
+	for _, test := range tests {
+		err := writer.Render(&bytes.Buffer{}, &weather, RenderOptions{Template: test.template})
+		if err == nil || err.Error() != test.message || ExitCode(err) != ExitUsage {
+			t.Errorf("Error in template error of %q: %v", test.template, err)
+		}
+	}
+
+	if err := writer.Render(&bytes.Buffer{}, &weather, RenderOptions{}); err == nil || !strings.Contains(err.Error(), "--template") {
+		t.Error("Error in missing template")
+	}
+}
+
+func TestTemplateOptions(t *testing.T) {
+	format, file, empty, indent := "pretty", "/nonexistent/goweather.tmpl", "", false
+	Format, Template, TemplateFile, Units, Timezone, Delimiter, RawIndent = &format, &empty, &file, &empty, &empty, &empty, &indent
+
+	if opts, err := NewRenderOptions("current"); err != nil || opts.Template != "" {
+		t.Error("Error in template file of another format, it should not be read")
+	}
+
+	format = "template"
+	if _, err := NewRenderOptions("current"); ExitCode(err) != ExitUsage {
+		t.Error("Error in missing template file")
+	}
+}
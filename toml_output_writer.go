@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+func init() {
+	RegisterFormat(OutputFormat{
+		Name:         "toml",
+		Description:  "the records of the JSON output as TOML, errors included",
+		New:          func() OutputWriterInterface { return &TomlOutputWriter{} },
+		StdoutErrors: true,
+	})
+}
+
+// TomlOutputWriter prints the records of the JSON output as a TOML document.
+// The current weather is the top level table, the rows of the forecasts and of
+// several locations are the array of tables records. TOML has no null, the
+// unknown values are left out.
+type TomlOutputWriter struct {
+}
+
+func (t *TomlOutputWriter) Render(out io.Writer, w *WeatherResponse, opts RenderOptions) error {
+	return t.print(out, WeatherRecord(w, opts.Lookup, opts.Response, opts))
+}
+
+func (t *TomlOutputWriter) RenderForecast(out io.Writer, f *ForecastResponse, opts RenderOptions) error {
+	return t.print(out, ForecastRecords(f, opts.Lookup, opts.Response, opts))
+}
+
+func (t *TomlOutputWriter) RenderDaily(out io.Writer, d *DailyForecast, opts RenderOptions) error {
+	return t.print(out, DailyRecords(d, opts.Lookup, opts.Response, opts))
+}
+
+func (t *TomlOutputWriter) RenderLocations(out io.Writer, results []LocationResult, opts RenderOptions) error {
+	return t.print(out, LocationRecords(results, opts))
+}
+
+// RenderError prints the error as a TOML table to stdout like the json format.
+func (t *TomlOutputWriter) RenderError(out io.Writer, err error, opts RenderOptions) error {
+	return t.print(out, newErrorRecord(err))
+}
+
+func (t *TomlOutputWriter) print(out io.Writer, v interface{}) error {
+	tree, err := orderedJSON(v)
+	if err != nil {
+		return err
+	}
+	if array, ok := tree.([]interface{}); ok {
+		tree = orderedObject{{Key: "records", Value: array}}
+	}
+	table, ok := tree.(orderedObject)
+	if !ok {
+		return fmt.Errorf("toml: the top level must be a table")
+	}
+
+	var b bytes.Buffer
+	t.table(&b, "", "", table)
+	_, err = io.WriteString(out, strings.TrimPrefix(b.String(), "\n"))
+	return err
+}
+
+// table writes the header and the key/value pairs of the table first, then
+// its sub-tables and arrays of tables, since everything after a header belongs
+// to it. The header of a table of only sub-tables is left out.
+func (t *TomlOutputWriter) table(b *bytes.Buffer, path, header string, table orderedObject) {
+	var values, tables []orderedField
+	for _, field := range table {
+		if _, ok := tomlValue(field.Value); ok {
+			values = append(values, field)
+		} else if field.Value != nil {
+			tables = append(tables, field)
+		}
+	}
+
+	if header != "" && (len(values) > 0 || len(tables) == 0) {
+		b.WriteString("\n" + header + "\n")
+	}
+	for _, field := range values {
+		value, _ := tomlValue(field.Value)
+		fmt.Fprintf(b, "%s = %s\n", quoteKey(field.Key), value)
+	}
+
+	for _, field := range tables {
+		key := tomlPath(path, field.Key)
+		switch value := field.Value.(type) {
+		case orderedObject:
+			t.table(b, key, "["+key+"]", value)
+		case []interface{}:
+			for _, item := range value {
+				if item, ok := item.(orderedObject); ok {
+					fmt.Fprintf(b, "\n[[%s]]\n", key)
+					t.table(b, key, "", item)
+				}
+			}
+		}
+	}
+}
+
+func tomlPath(path, key string) string {
+	if path == "" {
+		return quoteKey(key)
+	}
+	return path + "." + quoteKey(key)
+}
+
+// tomlValue returns the value of a key/value pair: a scalar or an inline
+// array of scalars. ok is false for the tables, the arrays of tables and null.
+func tomlValue(v interface{}) (value string, ok bool) {
+	switch v := v.(type) {
+	case bool:
+		return fmt.Sprint(v), true
+	case json.Number:
+		return v.String(), true
+	case string:
+		return quoteString(v), true
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			value, ok := tomlValue(item)
+			if !ok {
+				return "", false
+			}
+			values = append(values, value)
+		}
+		return "[" + strings.Join(values, ", ") + "]", true
+	}
+	return "", false
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const weatherJson = `{"coord":{"lon":-0.13,"lat":51.51},"weather":[],"base":"stations","main":{"temp":12.3,"feels_like":11.1,"temp_min":11,"temp_max":13.5,"pressure":1012,"humidity":81,"sea_level":1012,"grnd_level":1008},"visibility":10000,"wind":{"speed":4.1,"deg":247.5,"gust":8.2},"rain":{"1h":0.25},"dt":1541234567,"sys":{"type":1,"id":1414,"country":"GB","sunrise":1541228000,"sunset":1541262000},"timezone":0,"id":2643743,"name":"London","cod":200}`
+
+func TestDecodeWeather(t *testing.T) {
+	var weather WeatherResponse
+	if err := json.NewDecoder(strings.NewReader(weatherJson)).Decode(&weather); err != nil {
+		t.Fatal(err)
+	}
+
+	if rain, ok := weather.Rain.Volume("1h"); !ok || rain != 0.25 {
+		t.Error("Error in fractional rain")
+	}
+
+	if _, ok := weather.Snow.Volume("1h"); ok {
+		t.Error("Error in absent snow")
+	}
+
+	if weather.Clouds != nil {
+		t.Error("Error in absent clouds")
+	}
+
+	if weather.Main.FeelsLike == nil || *weather.Main.FeelsLike != 11.1 {
+		t.Error("Error in feels like")
+	}
+
+	if weather.Wind.GustSpeed() != 8.2 || WindDirection(weather.Wind.Deg) != "W" {
+		t.Error("Error in wind")
+	}
+
+	if weather.Timezone == nil || *weather.Timezone != 0 {
+		t.Error("Error in timezone")
+	}
+
+	if weather.Description() != "unknown" {
+		t.Error("Error in empty weather")
+	}
+}
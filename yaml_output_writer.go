@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+func init() {
+	RegisterFormat(OutputFormat{
+		Name:         "yaml",
+		Description:  "the records of the JSON output as YAML, errors included",
+		New:          func() OutputWriterInterface { return &YamlOutputWriter{} },
+		StdoutErrors: true,
+	})
+}
+
+// YamlOutputWriter prints the records of the JSON output as a YAML document, a
+// mapping of the current weather or a sequence of the rows.
+type YamlOutputWriter struct {
+}
+
+func (y *YamlOutputWriter) Render(out io.Writer, w *WeatherResponse, opts RenderOptions) error {
+	return y.print(out, WeatherRecord(w, opts.Lookup, opts.Response, opts))
+}
+
+func (y *YamlOutputWriter) RenderForecast(out io.Writer, f *ForecastResponse, opts RenderOptions) error {
+	return y.print(out, ForecastRecords(f, opts.Lookup, opts.Response, opts))
+}
+
+func (y *YamlOutputWriter) RenderDaily(out io.Writer, d *DailyForecast, opts RenderOptions) error {
+	return y.print(out, DailyRecords(d, opts.Lookup, opts.Response, opts))
+}
+
+func (y *YamlOutputWriter) RenderLocations(out io.Writer, results []LocationResult, opts RenderOptions) error {
+	return y.print(out, LocationRecords(results, opts))
+}
+
+// RenderError prints the error as a YAML mapping to stdout like the json
+// format.
+func (y *YamlOutputWriter) RenderError(out io.Writer, err error, opts RenderOptions) error {
+	return y.print(out, newErrorRecord(err))
+}
+
+func (y *YamlOutputWriter) print(out io.Writer, v interface{}) error {
+	tree, err := orderedJSON(v)
+	if err != nil {
+		return err
+	}
+
+	b := bufio.NewWriter(out)
+	if scalar, ok := yamlScalar(tree); ok {
+		fmt.Fprintln(b, scalar)
+	}
+	for _, line := range yamlLines(tree) {
+		fmt.Fprintln(b, line)
+	}
+	return b.Flush()
+}
+
+// yamlLines returns the lines of a mapping or a sequence in block style.
+func yamlLines(v interface{}) []string {
+	var lines []string
+	switch v := v.(type) {
+	case orderedObject:
+		for _, field := range v {
+			if scalar, ok := yamlScalar(field.Value); ok {
+				lines = append(lines, quoteKey(field.Key)+": "+scalar)
+				continue
+			}
+			lines = append(lines, quoteKey(field.Key)+":")
+			for _, line := range yamlLines(field.Value) {
+				lines = append(lines, "  "+line)
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if scalar, ok := yamlScalar(item); ok {
+				lines = append(lines, "- "+scalar)
+				continue
+			}
+			for i, line := range yamlLines(item) {
+				if i == 0 {
+					lines = append(lines, "- "+line)
+				} else {
+					lines = append(lines, "  "+line)
+				}
+			}
+		}
+	}
+	return lines
+}
+
+// yamlPlain are the strings which can be written without quotes.
+var yamlPlain = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9 _./()-]*[A-Za-z0-9)]$|^[A-Za-z]$`)
+
+// yamlScalar returns a value which fits on the line of its key, the empty
+// mappings and sequences included. ok is false for the others.
+func yamlScalar(v interface{}) (scalar string, ok bool) {
+	switch v := v.(type) {
+	case orderedObject:
+		return "{}", len(v) == 0
+	case []interface{}:
+		return "[]", len(v) == 0
+	case nil:
+		return "null", true
+	case bool:
+		return fmt.Sprint(v), true
+	case json.Number:
+		return v.String(), true
+	case string:
+		switch strings.ToLower(v) {
+		case "true", "false", "yes", "no", "on", "off", "null", "y", "n":
+			return quoteString(v), true
+		}
+		if yamlPlain.MatchString(v) {
+			return v, true
+		}
+		return quoteString(v), true
+	}
+	return "", false
+}